@@ -0,0 +1,219 @@
+package shrek
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// attestationHeader is the first line of an attestation. It's part of the signed
+	// data, so a signature over an attestation can't be mistaken for a signature over
+	// anything else.
+	attestationHeader = "onion-attestation-v1"
+
+	attestationTimeFormat = time.RFC3339
+)
+
+var (
+	// ErrInvalidSignature is returned by Attestation.Verify when the signature doesn't
+	// match the attestation.
+	ErrInvalidSignature = errors.New("shrek: attestation signature is not valid")
+
+	// ErrAttestationExpired is returned by Attestation.Verify when the attestation has
+	// expired, or isn't valid yet.
+	ErrAttestationExpired = errors.New("shrek: attestation is not valid at this time")
+)
+
+// Attestation is a statement signed by the key of an onion address, such as "this onion
+// belongs to example.com". Publishing it on both sides cross-links a clearnet identity
+// with an onion identity, because only the owner of the onion key can make it.
+//
+// In text form, as returned by MarshalText, an attestation looks like:
+//
+//   onion-attestation-v1
+//   onion: food7pzkbm3a6rj5ubjxgcwfx4xhrzekdzl4ljtrepuvmh7woo2ilkid.onion
+//   statement: this onion belongs to example.com
+//   issued: 2022-04-01T12:00:00Z
+//   expires: 2023-04-01T12:00:00Z
+//   signature: <base64 Ed25519 signature>
+//
+// The signature covers every line before it, exactly as shown. The expires line is
+// left out if the attestation never expires.
+type Attestation struct {
+	// HostName is the onion address that made the attestation, with the .onion TLD.
+	HostName string
+
+	// Statement is what's being attested to. It must be a single line.
+	Statement string
+
+	// Issued is when the attestation was made.
+	Issued time.Time
+
+	// Expires is when the attestation stops being valid. The zero value means it never
+	// expires.
+	Expires time.Time
+
+	// Signature is the signature of the onion address over the other fields.
+	Signature []byte
+}
+
+// Attest makes an attestation of the statement, signed by the address, that is valid
+// from issued until expires. If expires is the zero time, the attestation never
+// expires. Times are stored with a precision of 1 second.
+func (addr *OnionAddress) Attest(statement string, issued, expires time.Time) (*Attestation, error) {
+	a := &Attestation{
+		HostName:  addr.HostNameString(),
+		Statement: statement,
+		Issued:    issued.UTC().Truncate(time.Second),
+	}
+	if !expires.IsZero() {
+		a.Expires = expires.UTC().Truncate(time.Second)
+	}
+
+	body, err := a.signedData()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.Signature, err = addr.Sign(body); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Verify checks that the attestation was signed by the key of the onion address in it,
+// and that it's valid at the given time. It returns ErrInvalidSignature or
+// ErrAttestationExpired if it isn't.
+func (a *Attestation) Verify(now time.Time) error {
+	addr, err := ParseHostName(a.HostName)
+	if err != nil {
+		return err
+	}
+
+	body, err := a.signedData()
+	if err != nil {
+		return err
+	}
+
+	if !Verify(addr.PublicKey, body, a.Signature) {
+		return ErrInvalidSignature
+	}
+
+	if now.Before(a.Issued) || (!a.Expires.IsZero() && !now.Before(a.Expires)) {
+		return ErrAttestationExpired
+	}
+
+	return nil
+}
+
+// MarshalText encodes the attestation in its text form.
+func (a *Attestation) MarshalText() ([]byte, error) {
+	body, err := a.signedData()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(body, "signature: "+base64.StdEncoding.EncodeToString(a.Signature)+"\n"...), nil
+}
+
+// UnmarshalText decodes an attestation from its text form. It doesn't check the
+// signature; use Verify for that.
+func (a *Attestation) UnmarshalText(text []byte) error {
+	parsed, err := ParseAttestation(text)
+	if err != nil {
+		return err
+	}
+
+	*a = *parsed
+	return nil
+}
+
+// ParseAttestation parses an attestation from its text form, as returned by
+// MarshalText. It doesn't check the signature; use Verify for that.
+func ParseAttestation(data []byte) (*Attestation, error) {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+
+	var a Attestation
+	seen := make(map[string]bool)
+	header := false
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		if !header {
+			if line != attestationHeader {
+				return nil, fmt.Errorf("shrek: attestation must start with %q", attestationHeader)
+			}
+			header = true
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("shrek: attestation line is not valid: %q", line)
+		}
+		key, value := parts[0], strings.TrimSpace(parts[1])
+		if seen[key] {
+			return nil, fmt.Errorf("shrek: attestation has more than 1 %q line", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "onion":
+			a.HostName = value
+		case "statement":
+			a.Statement = value
+		case "issued":
+			a.Issued, err = time.Parse(attestationTimeFormat, value)
+		case "expires":
+			a.Expires, err = time.Parse(attestationTimeFormat, value)
+		case "signature":
+			a.Signature, err = base64.StdEncoding.DecodeString(value)
+		default:
+			return nil, fmt.Errorf("shrek: attestation has unknown line: %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("shrek: attestation %s is not valid: %w", key, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("shrek: could not read attestation: %w", err)
+	}
+
+	for _, key := range []string{"onion", "statement", "issued", "signature"} {
+		if !seen[key] {
+			return nil, fmt.Errorf("shrek: attestation is missing the %q line", key)
+		}
+	}
+
+	return &a, nil
+}
+
+// signedData returns the part of the text form that is signed.
+func (a *Attestation) signedData() ([]byte, error) {
+	if strings.ContainsAny(a.Statement, "\r\n") {
+		return nil, errors.New("shrek: attestation statement must be a single line")
+	}
+	if strings.TrimSpace(a.Statement) != a.Statement || a.Statement == "" {
+		return nil, errors.New("shrek: attestation statement must not be empty or start or end with spaces")
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, attestationHeader)
+	fmt.Fprintf(&buf, "onion: %s\n", a.HostName)
+	fmt.Fprintf(&buf, "statement: %s\n", a.Statement)
+	fmt.Fprintf(&buf, "issued: %s\n", a.Issued.UTC().Format(attestationTimeFormat))
+	if !a.Expires.IsZero() {
+		fmt.Fprintf(&buf, "expires: %s\n", a.Expires.UTC().Format(attestationTimeFormat))
+	}
+
+	return buf.Bytes(), nil
+}
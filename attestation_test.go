@@ -0,0 +1,97 @@
+package shrek_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/innix/shrek"
+)
+
+func TestOnionAddress_Attest(t *testing.T) {
+	t.Parallel()
+
+	addr, err := shrek.GenerateOnionAddress(bytes.NewBufferString(seed))
+	if err != nil {
+		t.Fatalf("could not generate the prerequisite onion address: %v", err)
+	}
+
+	issued := time.Date(2022, time.April, 1, 12, 0, 0, 0, time.UTC)
+	expires := issued.AddDate(1, 0, 0)
+	a, err := addr.Attest("this onion belongs to example.com", issued, expires)
+	if err != nil {
+		t.Fatalf("could not make attestation: %v", err)
+	}
+
+	text, err := a.MarshalText()
+	if err != nil {
+		t.Fatalf("could not marshal attestation: %v", err)
+	}
+
+	parsed, err := shrek.ParseAttestation(text)
+	if err != nil {
+		t.Fatalf("could not parse attestation: %v\n%s", err, text)
+	}
+	if parsed.HostName != seedHostname+".onion" {
+		t.Errorf("unexpected hostname, got: %q, wanted: %q", parsed.HostName, seedHostname+".onion")
+	}
+
+	if err := parsed.Verify(issued.AddDate(0, 6, 0)); err != nil {
+		t.Errorf("expected attestation to verify, got: %v", err)
+	}
+	if err := parsed.Verify(expires); !errors.Is(err, shrek.ErrAttestationExpired) {
+		t.Errorf("expected ErrAttestationExpired after expiry, got: %v", err)
+	}
+	if err := parsed.Verify(issued.Add(-time.Second)); !errors.Is(err, shrek.ErrAttestationExpired) {
+		t.Errorf("expected ErrAttestationExpired before issue, got: %v", err)
+	}
+
+	// Changing any part of the attestation must break the signature.
+	tampered, err := shrek.ParseAttestation([]byte(strings.Replace(string(text), "example.com", "example.org", 1)))
+	if err != nil {
+		t.Fatalf("could not parse tampered attestation: %v", err)
+	}
+	if err := tampered.Verify(issued); !errors.Is(err, shrek.ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature for tampered attestation, got: %v", err)
+	}
+
+	other, err := shrek.GenerateOnionAddress(nil)
+	if err != nil {
+		t.Fatalf("could not generate the prerequisite onion address: %v", err)
+	}
+	tampered.Statement = parsed.Statement
+	tampered.HostName = other.HostNameString()
+	if err := tampered.Verify(issued); !errors.Is(err, shrek.ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature for attestation claimed by another onion, got: %v", err)
+	}
+}
+
+func TestParseAttestation_Invalid(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		Name string
+		Text string
+	}{
+		{Name: "empty", Text: ""},
+		{Name: "no header", Text: "onion: x\n"},
+		{Name: "missing signature", Text: "onion-attestation-v1\nonion: x\nstatement: y\nissued: 2022-04-01T12:00:00Z\n"},
+		{Name: "bad time", Text: "onion-attestation-v1\nonion: x\nstatement: y\nissued: yesterday\nsignature: AA==\n"},
+		{Name: "unknown line", Text: "onion-attestation-v1\nonion: x\nstatement: y\nissued: 2022-04-01T12:00:00Z\nsignature: AA==\nfoo: bar\n"},
+		{Name: "duplicate line", Text: "onion-attestation-v1\nonion: x\nonion: x\nstatement: y\nissued: 2022-04-01T12:00:00Z\nsignature: AA==\n"},
+	}
+
+	for _, tc := range table {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := shrek.ParseAttestation([]byte(tc.Text)); err == nil {
+				t.Errorf("expected error, got nil")
+			}
+		})
+	}
+}
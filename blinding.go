@@ -0,0 +1,148 @@
+package shrek
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/innix/shrek/internal/ed25519"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	// DefaultTimePeriodLength is the length of a time period, in minutes, used by the Tor
+	// network unless its consensus says otherwise.
+	DefaultTimePeriodLength = 1440
+
+	// timePeriodRotationOffset is how far, in minutes, the start of every time period is
+	// offset from midnight UTC.
+	timePeriodRotationOffset = 12 * 60
+
+	// ed25519Basepoint is how rend-spec-v3 writes the Ed25519 basepoint when it's used
+	// as an input to a hash.
+	ed25519Basepoint = "(15112221349535400772501151409588531511454012693041857206046113283949847762202, " +
+		"46316835694926478169428394003475163141307993866256225615783033603165251855960)"
+
+	// blindString is "Derive temporary signing key" followed by a zero byte.
+	blindString = "Derive temporary signing key\x00"
+)
+
+// TimePeriod is one of the periods of time that onion services use a different blinded
+// key in, as described in section 2.2.1 of rend-spec-v3.
+type TimePeriod struct {
+	// Number is the number of the time period, counting from the Unix epoch.
+	Number uint64
+
+	// Length is the length of the time period in minutes.
+	Length uint64
+}
+
+// TimePeriodAt returns the time period, of the given length in minutes, that t is in.
+// If length is zero, DefaultTimePeriodLength is used.
+func TimePeriodAt(t time.Time, length uint64) TimePeriod {
+	if length == 0 {
+		length = DefaultTimePeriodLength
+	}
+
+	minutes := uint64(t.Unix()/60) - timePeriodRotationOffset
+	return TimePeriod{Number: minutes / length, Length: length}
+}
+
+// Start returns the time that the time period starts at.
+func (p TimePeriod) Start() time.Time {
+	minutes := p.Number*p.Length + timePeriodRotationOffset
+	return time.Unix(int64(minutes)*60, 0).UTC()
+}
+
+// End returns the time that the time period ends at, which is also the start of the
+// next time period.
+func (p TimePeriod) End() time.Time {
+	return p.Start().Add(time.Duration(p.Length) * time.Minute)
+}
+
+// BlindedPublicKey returns the blinded public key that the onion service uses in the
+// time period, as described in appendix A.2 of rend-spec-v3. Its descriptors are
+// published under, and signed by, this key.
+func (addr *OnionAddress) BlindedPublicKey(period TimePeriod) ([]byte, error) {
+	if l := len(addr.PublicKey); l != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("shrek: public key has wrong length: %d", l)
+	}
+
+	// N = "key-blind" | INT_8(period-number) | INT_8(period_length)
+	nonce := make([]byte, 0, 9+8+8)
+	nonce = append(nonce, "key-blind"...)
+	nonce = appendUint64(nonce, period.Number)
+	nonce = appendUint64(nonce, period.Length)
+
+	// h = H(BLIND_STRING | A | s | B | N), where the secret s is empty.
+	h := sha3.New256()
+	h.Write([]byte(blindString))
+	h.Write(addr.PublicKey)
+	h.Write([]byte(ed25519Basepoint))
+	h.Write(nonce)
+
+	blinded, err := ed25519.BlindPublicKey(addr.PublicKey, h.Sum(nil))
+	if err != nil {
+		return nil, fmt.Errorf("shrek: could not blind public key: %w", err)
+	}
+
+	return blinded, nil
+}
+
+// Subcredential returns the subcredential of the onion service in the time period, as
+// described in section 2.1 of rend-spec-v3. It's used to encrypt the descriptor, and
+// by the introduction and rendezvous protocols.
+func (addr *OnionAddress) Subcredential(period TimePeriod) ([32]byte, error) {
+	blinded, err := addr.BlindedPublicKey(period)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	// credential = H("credential" | public-identity-key)
+	// subcredential = H("subcredential" | credential | blinded-public-key)
+	credential := sha3.Sum256(append([]byte("credential"), addr.PublicKey...))
+
+	h := sha3.New256()
+	h.Write([]byte("subcredential"))
+	h.Write(credential[:])
+	h.Write(blinded)
+
+	var sub [32]byte
+	h.Sum(sub[:0])
+
+	return sub, nil
+}
+
+// HSDirIndex returns the position on the hash ring of the given replica of the onion
+// service's descriptor in the time period, as described in section 2.2.3 of
+// rend-spec-v3. The descriptor is uploaded to the HSDirs that follow this position.
+// Tor uses 2 replicas, numbered from 1.
+func (addr *OnionAddress) HSDirIndex(period TimePeriod, replica uint64) ([32]byte, error) {
+	blinded, err := addr.BlindedPublicKey(period)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	// hs_index(replicanum) = H("store-at-idx" | blinded_public_key |
+	//     INT_8(replicanum) | INT_8(period_length) | INT_8(period_num))
+	h := sha3.New256()
+	h.Write([]byte("store-at-idx"))
+	h.Write(blinded)
+	h.Write(appendUint64(nil, replica))
+	h.Write(appendUint64(nil, period.Length))
+	h.Write(appendUint64(nil, period.Number))
+
+	var idx [32]byte
+	h.Sum(idx[:0])
+
+	return idx, nil
+}
+
+// appendUint64 appends v as an 8 byte big-endian integer, which rend-spec-v3 calls
+// INT_8.
+func appendUint64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+
+	return append(b, buf[:]...)
+}
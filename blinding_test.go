@@ -0,0 +1,102 @@
+package shrek_test
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/innix/shrek"
+)
+
+func TestTimePeriodAt(t *testing.T) {
+	t.Parallel()
+
+	// The example from section 2.2.1 of rend-spec-v3.
+	now := time.Date(2016, time.April, 13, 11, 15, 1, 0, time.UTC)
+	period := shrek.TimePeriodAt(now, 0)
+
+	if want := (shrek.TimePeriod{Number: 16903, Length: 1440}); period != want {
+		t.Fatalf("unexpected time period, got: %+v, wanted: %+v", period, want)
+	}
+	if want := time.Date(2016, time.April, 12, 12, 0, 0, 0, time.UTC); !period.Start().Equal(want) {
+		t.Errorf("unexpected start, got: %v, wanted: %v", period.Start(), want)
+	}
+	if want := time.Date(2016, time.April, 13, 12, 0, 0, 0, time.UTC); !period.End().Equal(want) {
+		t.Errorf("unexpected end, got: %v, wanted: %v", period.End(), want)
+	}
+
+	// Time periods start at 12:00 UTC.
+	if n := shrek.TimePeriodAt(period.End(), 0).Number; n != 16904 {
+		t.Errorf("unexpected time period at end of period, got: %d, wanted: %d", n, 16904)
+	}
+}
+
+// The key, time period, blinded key and subcredential are known-answer vectors that were
+// generated with Tor, from the key_blinding_testvec test of arti's tor-hscrypto crate.
+const (
+	blindingTestPublicKey     = "833990b085c1a688c1d4c8b1f6b56afaf5a2eca674449e1d704f83765ccb7bc6"
+	blindingTestBlindedKey    = "3a50bf210e8f9ee955ae0014f7a6917fb65ebf098a86305abb508d1a7291b6d5"
+	blindingTestSubcredential = "635d55907816e8d76398a675a50b1c2f3e36b42a5ca77ba3a0441285161ae07d"
+)
+
+func TestOnionAddress_BlindedPublicKey(t *testing.T) {
+	t.Parallel()
+
+	pk, _ := hex.DecodeString(blindingTestPublicKey)
+	addr := &shrek.OnionAddress{PublicKey: pk}
+
+	period := shrek.TimePeriodAt(time.Date(1973, time.May, 20, 1, 50, 33, 0, time.UTC), 0)
+	if want := (shrek.TimePeriod{Number: 1234, Length: 1440}); period != want {
+		t.Fatalf("unexpected time period, got: %+v, wanted: %+v", period, want)
+	}
+
+	blinded, err := addr.BlindedPublicKey(period)
+	if err != nil {
+		t.Fatalf("could not blind public key: %v", err)
+	}
+	if got := hex.EncodeToString(blinded); got != blindingTestBlindedKey {
+		t.Errorf("unexpected blinded key, got: %s, wanted: %s", got, blindingTestBlindedKey)
+	}
+
+	sub, err := addr.Subcredential(period)
+	if err != nil {
+		t.Fatalf("could not compute subcredential: %v", err)
+	}
+	if got := hex.EncodeToString(sub[:]); got != blindingTestSubcredential {
+		t.Errorf("unexpected subcredential, got: %s, wanted: %s", got, blindingTestSubcredential)
+	}
+
+	if _, err := (&shrek.OnionAddress{PublicKey: pk[1:]}).BlindedPublicKey(period); err == nil {
+		t.Errorf("expected error blinding a public key with the wrong length")
+	}
+}
+
+func TestOnionAddress_HSDirIndex(t *testing.T) {
+	t.Parallel()
+
+	pk, _ := hex.DecodeString(blindingTestPublicKey)
+	addr := &shrek.OnionAddress{PublicKey: pk}
+	period := shrek.TimePeriod{Number: 1234, Length: 1440}
+
+	// Tor doesn't publish vectors for the descriptor's position on the hash ring, so
+	// these are pinned values. The blinded key they're built from is checked against
+	// Tor's vectors above, and the rest is one hash of it, as laid out in section 2.2.3
+	// of rend-spec-v3.
+	table := []struct {
+		Replica uint64
+		Want    string
+	}{
+		{Replica: 1, Want: "2947a8923f9816484cc5da790781d92ae4d56329f439deed14d894131e15cc11"},
+		{Replica: 2, Want: "b285d93ceb3625b160cb56dcdbf7446b533a578bddff27c971f7e97ca3e90045"},
+	}
+
+	for _, tc := range table {
+		idx, err := addr.HSDirIndex(period, tc.Replica)
+		if err != nil {
+			t.Fatalf("could not compute hsdir index: %v", err)
+		}
+		if got := hex.EncodeToString(idx[:]); got != tc.Want {
+			t.Errorf("unexpected hsdir index for replica %d, got: %s, wanted: %s", tc.Replica, got, tc.Want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package shrek
+
+import (
+	cryptorand "crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+const (
+	// ClientAuthKeySize is the size, in bytes, of the keys in a ClientAuthKey.
+	ClientAuthKeySize = curve25519.ScalarSize
+
+	clientAuthPrefix = "descriptor:x25519:"
+)
+
+// clientAuthB32 is the encoding Tor uses for client authorization keys: standard
+// uppercase base32, without padding.
+var clientAuthB32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// ClientAuthKey is an x25519 key pair used for v3 onion service client authorization.
+// The onion service is given the public key, and the client keeps the private key.
+type ClientAuthKey struct {
+	PublicKey  [ClientAuthKeySize]byte
+	PrivateKey [ClientAuthKeySize]byte
+}
+
+// GenerateClientAuthKey generates a new client authorization key pair. If rand is nil,
+// crypto/rand is used.
+func GenerateClientAuthKey(rand io.Reader) (*ClientAuthKey, error) {
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+
+	sk := make([]byte, ClientAuthKeySize)
+	if _, err := io.ReadFull(rand, sk); err != nil {
+		return nil, fmt.Errorf("shrek: could not generate client auth key: %w", err)
+	}
+
+	return NewClientAuthKey(sk)
+}
+
+// NewClientAuthKey creates a client authorization key pair from a 32 byte x25519
+// private key. The private key is clamped the same way Tor clamps it.
+func NewClientAuthKey(privateKey []byte) (*ClientAuthKey, error) {
+	if l := len(privateKey); l != ClientAuthKeySize {
+		return nil, fmt.Errorf("shrek: client auth private key has wrong length: %d", l)
+	}
+
+	var k ClientAuthKey
+	copy(k.PrivateKey[:], privateKey)
+	k.PrivateKey[0] &= 248
+	k.PrivateKey[31] &= 127
+	k.PrivateKey[31] |= 64
+
+	pk, err := curve25519.X25519(k.PrivateKey[:], curve25519.Basepoint)
+	if err != nil {
+		return nil, fmt.Errorf("shrek: could not compute client auth public key: %w", err)
+	}
+	copy(k.PublicKey[:], pk)
+
+	return &k, nil
+}
+
+// AuthFile returns the contents of the .auth file that goes in the authorized_clients
+// directory of the onion service, i.e. "descriptor:x25519:" followed by the base32
+// encoded public key.
+func (k *ClientAuthKey) AuthFile() []byte {
+	return []byte(clientAuthPrefix + clientAuthB32.EncodeToString(k.PublicKey[:]) + "\n")
+}
+
+// AuthPrivateFile returns the contents of the .auth_private file that goes in the
+// ClientOnionAuthDir of the client, i.e. the hostname of the address without the .onion
+// TLD, then ":descriptor:x25519:", then the base32 encoded private key.
+func (k *ClientAuthKey) AuthPrivateFile(addr *OnionAddress) []byte {
+	hostname := strings.TrimSuffix(addr.HostNameString(), ".onion")
+
+	return []byte(hostname + ":" + clientAuthPrefix + clientAuthB32.EncodeToString(k.PrivateKey[:]) + "\n")
+}
+
+// ParseAuthPrivateFile parses the contents of a .auth_private file, and returns the
+// onion address it's for, which has no secret key, and the client authorization key.
+func ParseAuthPrivateFile(data []byte) (*OnionAddress, *ClientAuthKey, error) {
+	parts := strings.SplitN(strings.TrimSpace(string(data)), ":", 2)
+	if len(parts) != 2 || !strings.HasPrefix(parts[1], clientAuthPrefix) {
+		return nil, nil, fmt.Errorf("shrek: auth private file must have the form <onion>:%s<key>", clientAuthPrefix)
+	}
+
+	addr, err := ParseHostName(parts[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sk, err := clientAuthB32.DecodeString(strings.TrimPrefix(parts[1], clientAuthPrefix))
+	if err != nil {
+		return nil, nil, fmt.Errorf("shrek: client auth private key is not valid base32: %w", err)
+	}
+
+	k, err := NewClientAuthKey(sk)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return addr, k, nil
+}
@@ -0,0 +1,70 @@
+package shrek_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/innix/shrek"
+)
+
+func TestNewClientAuthKey(t *testing.T) {
+	t.Parallel()
+
+	// Test vector from RFC 7748, section 6.1.
+	sk, _ := hex.DecodeString("77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a")
+	wantPK, _ := hex.DecodeString("8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a")
+
+	k, err := shrek.NewClientAuthKey(sk)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(k.PublicKey[:], wantPK) {
+		t.Errorf("unexpected public key, got: %x, wanted: %x", k.PublicKey, wantPK)
+	}
+
+	if _, err := shrek.NewClientAuthKey(sk[1:]); err == nil {
+		t.Errorf("expected error for short private key")
+	}
+}
+
+func TestClientAuthKey_Files(t *testing.T) {
+	t.Parallel()
+
+	addr, err := shrek.GenerateOnionAddress(bytes.NewBufferString(seed))
+	if err != nil {
+		t.Fatalf("could not generate the prerequisite onion address: %v", err)
+	}
+
+	k, err := shrek.GenerateClientAuthKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate client auth key: %v", err)
+	}
+
+	// 32 bytes is 52 chars of unpadded base32.
+	auth := string(k.AuthFile())
+	if !strings.HasPrefix(auth, "descriptor:x25519:") || len(strings.TrimSpace(auth)) != 18+52 {
+		t.Errorf("unexpected auth file: %q", auth)
+	}
+	if key := strings.TrimPrefix(auth, "descriptor:x25519:"); key != strings.ToUpper(key) || strings.Contains(key, "=") {
+		t.Errorf("expected uppercase base32 without padding: %q", auth)
+	}
+
+	authPrivate := k.AuthPrivateFile(addr)
+	if !strings.HasPrefix(string(authPrivate), seedHostname+":descriptor:x25519:") {
+		t.Errorf("unexpected auth private file: %q", authPrivate)
+	}
+
+	gotAddr, gotKey, err := shrek.ParseAuthPrivateFile(authPrivate)
+	if err != nil {
+		t.Fatalf("could not parse auth private file: %v", err)
+	}
+	if !bytes.Equal(gotAddr.PublicKey, addr.PublicKey) {
+		t.Errorf("unexpected address, got: %s, wanted: %s", gotAddr.HostNameString(), addr.HostNameString())
+	}
+	if *gotKey != *k {
+		t.Errorf("unexpected key, got: %+v, wanted: %+v", gotKey, k)
+	}
+}
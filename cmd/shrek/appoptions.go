@@ -3,14 +3,23 @@ package main
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
 type appOptions struct {
 	NumAddresses  int
 	SaveDirectory string
+	Store         string
+	OutputArchive string
+	Torrc         bool
+	TorrcPorts    []string
+	TorrcDir      string
 	NumThreads    int
 	Formatting    formatting
 	Patterns      []string
+	SpecFile      string
+	Timeout       time.Duration
+	MaxAttempts   uint64
 }
 
 type formatting string
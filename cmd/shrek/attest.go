@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/innix/shrek"
+)
+
+func runAttest(args []string) int {
+	cmd := findCommand("attest")
+	flags, f := newCommandFlagSet(cmd)
+	dir := flags.StringP("dir", "", "", "key `dir`ectory of the onion address that makes the attestation")
+	statement := flags.StringP("statement", "", "", "the `text` to attest to, e.g. \"this onion belongs to example.com\"")
+	expires := flags.StringP("expires", "", "", "`time` the attestation expires, as a date or RFC 3339 time (default = never)")
+	validFor := flags.DurationP("valid-for", "", 0, "`duration` the attestation is valid for, instead of --expires")
+	output := flags.StringP("output", "o", "", "`file` to write the attestation to (default = stdout)")
+	parseCommandFlags(flags, f, args)
+
+	if *dir == "" || *statement == "" || flags.NArg() > 0 {
+		LogError("A --dir and a --statement must be provided.")
+		LogError("")
+		flags.Usage()
+		return exitUsage
+	}
+
+	issued := time.Now()
+	var expiry time.Time
+	switch {
+	case *expires != "" && *validFor != 0:
+		LogError("%s: --expires and --valid-for can't be used together.", color.RedString("Error"))
+		return exitUsage
+	case *expires != "":
+		t, err := parseExpiry(*expires)
+		if err != nil {
+			LogError("%s: %v.", color.RedString("Error"), err)
+			return exitUsage
+		}
+		expiry = t
+	case *validFor != 0:
+		expiry = issued.Add(*validFor)
+	}
+
+	addr, err := shrek.ReadOnionAddress(*dir)
+	if err != nil {
+		LogError("%s: Could not read key directory: %v.", color.RedString("Error"), err)
+		return exitError
+	}
+
+	a, err := addr.Attest(*statement, issued, expiry)
+	if err != nil {
+		LogError("%s: Could not make attestation: %v.", color.RedString("Error"), err)
+		return exitError
+	}
+
+	text, err := a.MarshalText()
+	if err != nil {
+		LogError("%s: Could not encode attestation: %v.", color.RedString("Error"), err)
+		return exitError
+	}
+
+	if *output == "" || *output == "-" {
+		_, err = os.Stdout.Write(text)
+	} else {
+		err = os.WriteFile(*output, text, 0o644)
+	}
+	if err != nil {
+		LogError("%s: Could not write attestation: %v.", color.RedString("Error"), err)
+		return exitError
+	}
+
+	return exitOK
+}
+
+func runVerifyAttestation(args []string) int {
+	cmd := findCommand("verify-attestation")
+	flags, f := newCommandFlagSet(cmd)
+	onion := flags.StringP("onion", "", "", "also require the attestation to be made by this onion `hostname`")
+	parseCommandFlags(flags, f, args)
+
+	if flags.NArg() > 1 {
+		LogError("At most 1 attestation file can be provided.")
+		LogError("")
+		flags.Usage()
+		return exitUsage
+	}
+
+	var data []byte
+	var err error
+	if name := flags.Arg(0); name == "" || name == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		LogError("%s: Could not read attestation: %v.", color.RedString("Error"), err)
+		return exitError
+	}
+
+	a, err := shrek.ParseAttestation(data)
+	if err != nil {
+		LogError("%s: Could not parse attestation: %v.", color.RedString("Error"), err)
+		return exitError
+	}
+
+	printField("Onion", "%s", a.HostName)
+	printField("Statement", "%s", a.Statement)
+	printField("Issued", "%s", a.Issued.Format(time.RFC3339))
+	if a.Expires.IsZero() {
+		printField("Expires", "%s", "never")
+	} else {
+		printField("Expires", "%s", a.Expires.Format(time.RFC3339))
+	}
+
+	if *onion != "" {
+		want, err := shrek.ParseHostName(*onion)
+		if err != nil {
+			LogError("%s: Could not parse --onion: %v.", color.RedString("Error"), err)
+			return exitUsage
+		}
+		if got, err := shrek.ParseHostName(a.HostName); err != nil || got.HostNameString() != want.HostNameString() {
+			printField("Result", "%s", color.RedString("FAIL, attestation is not by %s", want.HostNameString()))
+			return exitError
+		}
+	}
+
+	if err := a.Verify(time.Now()); err != nil {
+		printField("Result", "%s", color.RedString("FAIL, %s", trimErr(err)))
+		return exitError
+	}
+	printField("Result", "%s", color.GreenString("PASS"))
+
+	return exitOK
+}
+
+// parseExpiry parses an expiry time given as a date or an RFC 3339 time.
+func parseExpiry(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("expiry time '%s' is not a date or an RFC 3339 time", v)
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/innix/shrek"
+	"github.com/innix/shrek/keyiter"
+)
+
+// benchPattern is the search filter used by the bench command when none are given. It's
+// long enough that it never matches in practice, so the miner never stops early.
+const benchPattern = "shrekbenchmark"
+
+// benchRingSize is how many addresses are generated up front to run the encoding and
+// matching stages on, so that those stages are timed without the iterator.
+const benchRingSize = 1024
+
+func runBench(args []string) int {
+	cmd := findCommand("bench")
+	flags, f := newCommandFlagSet(cmd)
+	duration := flags.DurationP("duration", "d", 2*time.Second, "how long to run each stage and each thread count for, e.g. 5s")
+	maxThreads := flags.IntP("threads", "t", 0, "highest `num`ber of threads to try (default = all CPU cores)")
+	parseCommandFlags(flags, f, args)
+
+	if *duration <= 0 {
+		LogError("%s: Duration must be more than zero.", color.RedString("Error"))
+		return exitUsage
+	}
+	if *maxThreads <= 0 {
+		*maxThreads = runtime.NumCPU()
+	}
+	runtime.GOMAXPROCS(*maxThreads + 1) // +1 for main proc.
+
+	patterns := flags.Args()
+	if len(patterns) == 0 {
+		patterns = []string{benchPattern}
+	}
+	m, err := buildMatcher(patterns, "")
+	if err != nil {
+		LogError("%s: Could not build search filters: %v.", color.RedString("Error"), err)
+		return exitUsage
+	}
+	LogInfo("")
+
+	LogInfo("%sTime per key of each stage of the search, on 1 thread:", Pretty("⏱️  ", ""))
+	stages, err := benchStages(m, *duration)
+	if err != nil {
+		LogError("%s: %v.", color.RedString("Error"), err)
+		return exitError
+	}
+	var total time.Duration
+	for _, s := range stages {
+		printField(s.name, "%s", formatStageTime(s.perKey))
+		if !s.perMatch {
+			total += s.perKey
+		}
+	}
+	printField("Total", "%s %s", formatStageTime(total), color.HiBlackString("(exact encoding excluded; it only runs on approx matches)"))
+	LogInfo("")
+
+	LogInfo("%sKeys per second for each number of threads, over %s each:", Pretty("🔥 ", ""), *duration)
+	LogInfo("   %s", color.CyanString("%-8s %-14s %s", "Threads", "Total", "Per thread"))
+	var results []benchResult
+	for _, n := range benchThreadCounts(*maxThreads) {
+		r := benchThreads(m, n, *duration)
+		results = append(results, r)
+
+		LogInfo("   %-8d %s %s", n, color.GreenString("%-14s", formatRate(r.rate())), formatRate(r.rate()/float64(n)))
+	}
+	LogInfo("")
+
+	best := recommendThreads(results)
+	LogInfo("%sRecommended: %s %s",
+		Pretty("👍 ", ""),
+		color.GreenString("-t %d", best.threads),
+		color.HiBlackString("(%s keys/sec; fewest threads within 95%% of the fastest)", formatRate(best.rate())),
+	)
+
+	return exitOK
+}
+
+type benchStage struct {
+	name   string
+	perKey time.Duration
+
+	// perMatch is true if the stage only runs for keys whose approx hostname matches,
+	// rather than for every key.
+	perMatch bool
+}
+
+// benchStages times each stage of the miner's loop on its own, on the current
+// goroutine.
+func benchStages(m shrek.Matcher, d time.Duration) ([]benchStage, error) {
+	it, err := keyiter.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Destroy()
+
+	// Iterator advance: stepping to the next key and getting its public key.
+	advance := timeStage(d, func() {
+		_ = it.PublicKey()
+		it.Next()
+	})
+
+	// The other stages work on addresses made up front, so they don't time the
+	// iterator too.
+	addrs := make([]shrek.OnionAddress, benchRingSize)
+	approx := make([][]byte, benchRingSize)
+	for i := range addrs {
+		addrs[i].PublicKey = it.PublicKey()
+		approx[i] = make([]byte, shrek.EncodedPublicKeySize)
+		addrs[i].HostNameApprox(approx[i])
+		it.Next()
+	}
+
+	hostname := make([]byte, shrek.EncodedPublicKeySize)
+	i := 0
+	next := func() int {
+		i = (i + 1) % benchRingSize
+		return i
+	}
+
+	return []benchStage{
+		{name: "Iterator advance", perKey: advance},
+		{name: "Approx encoding", perKey: timeStage(d, func() {
+			addrs[next()].HostNameApprox(hostname)
+		})},
+		{name: "Matching", perKey: timeStage(d, func() {
+			m.MatchApprox(approx[next()])
+		})},
+		{name: "Exact encoding", perMatch: true, perKey: timeStage(d, func() {
+			addrs[next()].HostName(hostname)
+		})},
+	}, nil
+}
+
+// timeStage calls fn repeatedly for about d, and returns the average time per call.
+func timeStage(d time.Duration, fn func()) time.Duration {
+	// The clock is only read between rounds, so it doesn't add to the time of fn.
+	const round = 1024
+
+	var calls int64
+	start := time.Now()
+	for time.Since(start) < d {
+		for i := 0; i < round; i++ {
+			fn()
+		}
+		calls += round
+	}
+
+	return time.Since(start) / time.Duration(calls)
+}
+
+type benchResult struct {
+	threads  int
+	attempts uint64
+	elapsed  time.Duration
+}
+
+func (r benchResult) rate() float64 {
+	if secs := r.elapsed.Seconds(); secs > 0 {
+		return float64(r.attempts) / secs
+	}
+
+	return 0
+}
+
+// benchThreads runs the miner on n threads for d, and returns how many keys it tried.
+func benchThreads(m shrek.Matcher, n int, d time.Duration) benchResult {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	var attempts uint64
+	start := time.Now()
+	wg := runWorkGroup(n, func(id int) {
+		// Keep mining until the time is up, even if the filters are easy enough to
+		// match along the way.
+		for ctx.Err() == nil {
+			var res shrek.MineResult
+			_, _ = shrek.MineOnionHostNameWithOptions(ctx, m,
+				shrek.WithWorkerID(id),
+				shrek.WithResult(&res),
+			)
+			atomic.AddUint64(&attempts, res.Attempts)
+		}
+	})
+	wg.Wait()
+
+	return benchResult{threads: n, attempts: atomic.LoadUint64(&attempts), elapsed: time.Since(start)}
+}
+
+// benchThreadCounts returns the thread counts to try: powers of 2 up to max, and max.
+func benchThreadCounts(max int) []int {
+	var counts []int
+	for n := 1; n < max; n *= 2 {
+		counts = append(counts, n)
+	}
+
+	return append(counts, max)
+}
+
+// recommendThreads returns the result with the fewest threads that's within 95% of the
+// fastest result. More threads than that mostly just make the machine less usable.
+func recommendThreads(results []benchResult) benchResult {
+	fastest := results[0]
+	for _, r := range results {
+		if r.rate() > fastest.rate() {
+			fastest = r
+		}
+	}
+
+	for _, r := range results {
+		if r.rate() >= fastest.rate()*0.95 {
+			return r
+		}
+	}
+
+	return fastest
+}
+
+func formatStageTime(d time.Duration) string {
+	perSec := 0.0
+	if d > 0 {
+		perSec = float64(time.Second) / float64(d)
+	}
+
+	return fmt.Sprintf("%s %s", color.GreenString("%-12s", d.String()+"/key"), color.HiBlackString("(%s keys/sec)", formatRate(perSec)))
+}
+
+// formatRate formats a number of keys per second, e.g. 1234567 as "1.23M".
+func formatRate(r float64) string {
+	switch {
+	case r >= 1e6:
+		return fmt.Sprintf("%.2fM", r/1e6)
+	case r >= 1e3:
+		return fmt.Sprintf("%.2fK", r/1e3)
+	default:
+		return fmt.Sprintf("%.0f", r)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	"github.com/innix/shrek"
+)
+
+func runClientAuth(args []string) int {
+	cmd := findCommand("client-auth")
+	flags, f := newCommandFlagSet(cmd)
+	name := flags.StringP("name", "", "client", "`name` of the client, used for the file names")
+	output := flags.StringP("output", "o", "", "`dir`ectory to write the .auth and .auth_private files to (default = print them)")
+	install := flags.BoolP("install", "", false, "also add the .auth file to the authorized_clients directory of the key directory")
+	parseCommandFlags(flags, f, args)
+
+	if flags.NArg() != 1 {
+		LogError("Exactly 1 key directory or hostname must be provided.")
+		LogError("")
+		flags.Usage()
+		return exitUsage
+	}
+	if *name == "" || filepath.Base(*name) != *name {
+		LogError("%s: Client name '%s' is not valid.", color.RedString("Error"), *name)
+		return exitUsage
+	}
+
+	target := flags.Arg(0)
+	addr, err := readInfoTarget(target)
+	if err != nil {
+		LogError("%s: Could not read %s: %v.", color.RedString("Error"), target, err)
+		return exitError
+	}
+
+	key, err := shrek.GenerateClientAuthKey(nil)
+	if err != nil {
+		LogError("%s: %v.", color.RedString("Error"), err)
+		return exitError
+	}
+	auth, authPrivate := key.AuthFile(), key.AuthPrivateFile(addr)
+
+	if *install {
+		if fi, err := os.Stat(target); err != nil || !fi.IsDir() {
+			LogError("%s: --install needs a key directory, not a hostname.", color.RedString("Error"))
+			return exitUsage
+		}
+
+		dir := filepath.Join(target, "authorized_clients")
+		if err := os.MkdirAll(dir, wantDirMode); err != nil {
+			LogError("%s: Could not create directory: %v.", color.RedString("Error"), err)
+			return exitError
+		}
+		if err := writeNewFile(filepath.Join(dir, *name+".auth"), auth); err != nil {
+			LogError("%s: Could not write .auth file: %v.", color.RedString("Error"), err)
+			return exitError
+		}
+	}
+
+	if *output == "" {
+		LogInfo("%s", color.CyanString("# %s.auth", *name))
+		_, _ = os.Stdout.Write(auth)
+		LogInfo("%s", color.CyanString("# %s.auth_private", *name))
+		_, _ = os.Stdout.Write(authPrivate)
+		return exitOK
+	}
+
+	files := []struct {
+		ext  string
+		data []byte
+	}{
+		{ext: ".auth", data: auth},
+		{ext: ".auth_private", data: authPrivate},
+	}
+	for _, file := range files {
+		if err := writeNewFile(filepath.Join(*output, *name+file.ext), file.data); err != nil {
+			LogError("%s: Could not write %s file: %v.", color.RedString("Error"), file.ext, err)
+			return exitError
+		}
+	}
+
+	return exitOK
+}
+
+// writeNewFile writes data to a file that must not already exist, readable only by the
+// current user.
+func writeNewFile(name string, data []byte) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, wantFileMode)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
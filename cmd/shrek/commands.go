@@ -0,0 +1,145 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/innix/shrek"
+	"github.com/spf13/pflag"
+)
+
+type command struct {
+	Name    string
+	Usage   string
+	Summary string
+	Run     func(args []string) int
+}
+
+// commands are the subcommands that can be given as the first argument. Anything else
+// is treated as a search filter, so to search for an address that starts with the
+// name of a command, put "--" before it.
+var commands []command
+
+func init() {
+	commands = []command{
+		{
+			Name:    "verify",
+			Usage:   "[options] dir [more-dirs...]",
+			Summary: "check saved key directories, recursively, for problems",
+			Run:     runVerify,
+		},
+		{
+			Name:    "info",
+			Usage:   "[options] dir-or-hostname [more...]",
+			Summary: "print details about a key directory or an onion hostname",
+			Run:     runInfo,
+		},
+		{
+			Name:    "convert",
+			Usage:   "[options] --to format input",
+			Summary: "convert a key between Tor's key files, ADD_ONION, PEM, and JSON formats",
+			Run:     runConvert,
+		},
+		{
+			Name:    "torrc",
+			Usage:   "[options] dir [more-dirs...]",
+			Summary: "build a torrc that hosts the saved addresses in the given directories",
+			Run:     runTorrc,
+		},
+		{
+			Name:    "client-auth",
+			Usage:   "[options] dir-or-hostname",
+			Summary: "generate a v3 client authorization key pair for an address",
+			Run:     runClientAuth,
+		},
+		{
+			Name:    "attest",
+			Usage:   "[options] --dir dir --statement text",
+			Summary: "sign a statement, such as which website an onion belongs to, with an onion's key",
+			Run:     runAttest,
+		},
+		{
+			Name:    "verify-attestation",
+			Usage:   "[options] [file]",
+			Summary: "check an attestation made by the attest command, read from file or stdin",
+			Run:     runVerifyAttestation,
+		},
+		{
+			Name:    "bench",
+			Usage:   "[options] [filters...]",
+			Summary: "measure how many keys per second this machine can search, and how many threads to use",
+			Run:     runBench,
+		},
+	}
+}
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].Name == name {
+			return &commands[i]
+		}
+	}
+
+	return nil
+}
+
+// isFilter reports whether the command's name is also a valid search filter, so that
+// someone who runs it might have meant to search for it instead.
+func (cmd *command) isFilter() bool {
+	_, err := shrek.ParsePattern(cmd.Name)
+	return err == nil
+}
+
+// newCommandFlagSet creates a flag set for a subcommand, with the flags that every
+// subcommand has already added to it.
+func newCommandFlagSet(cmd *command) (*pflag.FlagSet, *formatting) {
+	flags := pflag.NewFlagSet(cmd.Name, pflag.ExitOnError)
+	flags.SortFlags = false
+
+	var f formatting
+	flags.VarP(&f, "format", "", "what `kind` of formatting to use (basic, colored, enhanced, default = all)")
+	flags.BoolP("help", "h", false, "show this help menu")
+
+	flags.Usage = func() {
+		LogError("Usage:")
+		LogError("  %s %s %s", filepath.Base(os.Args[0]), cmd.Name, cmd.Usage)
+		LogError("")
+		LogError("%s.", capitalize(cmd.Summary))
+		LogError("")
+		LogError("OPTIONS")
+		flags.PrintDefaults()
+	}
+
+	return flags, &f
+}
+
+// parseCommandFlags parses the args of a subcommand, then sets up logging based on the
+// flags. It exits the program if the help flag was given.
+func parseCommandFlags(flags *pflag.FlagSet, f *formatting, args []string) {
+	_ = flags.Parse(args) // Exits on error.
+
+	if help, _ := flags.GetBool("help"); help {
+		flags.Usage()
+		os.Exit(exitOK)
+	}
+
+	LogVerboseEnabled = true
+	LogPrettyEnabled = f.UseEnhanced()
+	color.NoColor = !f.UseColors()
+}
+
+func printCommands() {
+	LogError("COMMANDS")
+	for _, cmd := range commands {
+		LogError("  %-20s %s", cmd.Name, cmd.Summary)
+	}
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestCommand_IsFilter(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		Name string
+		Want bool
+	}{
+		{Name: "verify", Want: true},
+		{Name: "info", Want: true},
+		{Name: "bench", Want: true},
+		{Name: "attest", Want: true},
+		{Name: "client-auth", Want: false},
+		{Name: "verify-attestation", Want: false},
+	}
+
+	for _, tc := range table {
+		cmd := findCommand(tc.Name)
+		if cmd == nil {
+			t.Fatalf("command not found: %q", tc.Name)
+		}
+		if got := cmd.isFilter(); got != tc.Want {
+			t.Errorf("unexpected result for %q, got: %v, wanted: %v", tc.Name, got, tc.Want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/innix/shrek"
+)
+
+const (
+	keyFormatAuto    = "auto"
+	keyFormatDir     = "dir"
+	keyFormatControl = "control"
+	keyFormatPEM     = "pem"
+	keyFormatJSON    = "json"
+	keyFormatSecret  = "secret"
+)
+
+func runConvert(args []string) int {
+	cmd := findCommand("convert")
+	flags, f := newCommandFlagSet(cmd)
+	from := flags.StringP("from", "", keyFormatAuto, "`format` of the input (auto, dir, control, pem, json, secret)")
+	to := flags.StringP("to", "", "", "`format` to convert to (dir, control, pem, json)")
+	output := flags.StringP("output", "o", "", "`path` to write to; for dir, the directory to save in (default = stdout or cwd)")
+	force := flags.BoolP("force", "f", false, "for dir, replace the key directory if it already exists")
+	parseCommandFlags(flags, f, args)
+
+	if flags.NArg() != 1 || *to == "" {
+		LogError("Exactly 1 input and a --to format must be provided.")
+		LogError("")
+		flags.Usage()
+		return exitUsage
+	}
+
+	addr, err := readKey(flags.Arg(0), *from)
+	if err != nil {
+		LogError("%s: Could not read key: %v.", color.RedString("Error"), err)
+		return exitError
+	}
+
+	if *to == keyFormatDir {
+		if err := shrek.SaveOnionAddress(*output, addr, shrek.WithOverwrite(*force)); err != nil {
+			LogError("%s: Could not save key: %v.", color.RedString("Error"), err)
+			return exitError
+		}
+		return exitOK
+	}
+
+	data, err := encodeKey(addr, *to)
+	if err != nil {
+		LogError("%s: Could not convert key: %v.", color.RedString("Error"), err)
+		return exitError
+	}
+
+	if *output == "" || *output == "-" {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = os.WriteFile(*output, data, 0o600)
+	}
+	if err != nil {
+		LogError("%s: Could not write key: %v.", color.RedString("Error"), err)
+		return exitError
+	}
+
+	return exitOK
+}
+
+// readKey reads a key from input, which is a directory for the dir format, or a file
+// (or "-" for stdin) for every other format. The secret format is Tor's
+// hs_ed25519_secret_key file.
+func readKey(input, format string) (*shrek.OnionAddress, error) {
+	format = strings.ToLower(format)
+	if format == keyFormatAuto {
+		if fi, err := os.Stat(input); err == nil && fi.IsDir() {
+			format = keyFormatDir
+		}
+	}
+	if format == keyFormatDir {
+		return shrek.ReadOnionAddress(input)
+	}
+
+	var data []byte
+	var err error
+	if input == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(input)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Tor's key file is binary, so it's checked for before trimming, which could cut
+	// bytes off the end of the key.
+	if format == keyFormatAuto && bytes.HasPrefix(data, []byte("== ed25519v1-secret: type0 ==")) {
+		format = keyFormatSecret
+	}
+	if format == keyFormatSecret {
+		return shrek.ParseSecretKeyFile(data)
+	}
+	data = bytes.TrimSpace(data)
+
+	if format == keyFormatAuto {
+		switch {
+		case bytes.HasPrefix(data, []byte("ED25519-V3:")):
+			format = keyFormatControl
+		case bytes.HasPrefix(data, []byte("-----BEGIN")):
+			format = keyFormatPEM
+		case bytes.HasPrefix(data, []byte("{")):
+			format = keyFormatJSON
+		default:
+			return nil, fmt.Errorf("could not detect key format, use --from to set it")
+		}
+	}
+
+	switch format {
+	case keyFormatControl:
+		return shrek.ParseControlPortKey(string(data))
+	case keyFormatPEM:
+		return shrek.ParsePEM(data)
+	case keyFormatJSON:
+		var addr shrek.OnionAddress
+		if err := addr.UnmarshalJSON(data); err != nil {
+			return nil, err
+		}
+		return &addr, nil
+	default:
+		return nil, fmt.Errorf("unknown input format: %q", format)
+	}
+}
+
+func encodeKey(addr *shrek.OnionAddress, format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case keyFormatControl:
+		key, err := addr.ControlPortKey()
+		if err != nil {
+			return nil, err
+		}
+		return []byte(key + "\n"), nil
+	case keyFormatPEM:
+		LogError("%s: Only the public key can be converted to PEM. Tor secret keys are expanded "+
+			"keys, and the standard Ed25519 seed can't be recovered from them.",
+			color.YellowString("Warning"),
+		)
+		return addr.PublicKeyPEM()
+	case keyFormatJSON:
+		data, err := addr.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	default:
+		return nil, fmt.Errorf("unknown output format: %q", format)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/innix/shrek"
+)
+
+func runInfo(args []string) int {
+	cmd := findCommand("info")
+	flags, f := newCommandFlagSet(cmd)
+	hideSecret := flags.BoolP("hide-secret", "", false, "don't print the control port key")
+	timePeriod := flags.StringP("time-period", "", "", "also print the blinded key and subcredential for this time period `num`ber, or \"current\"")
+	periodLength := flags.Uint64P("period-length", "", 0, "length of a time period in `min`utes (default = 1440)")
+	parseCommandFlags(flags, f, args)
+
+	var period *shrek.TimePeriod
+	if *timePeriod != "" {
+		p, err := parseTimePeriod(*timePeriod, *periodLength)
+		if err != nil {
+			LogError("%s: %v.", color.RedString("Error"), err)
+			return exitUsage
+		}
+		period = &p
+	}
+
+	if flags.NArg() < 1 {
+		LogError("No key directories or hostnames provided.")
+		LogError("")
+		flags.Usage()
+		return exitUsage
+	}
+
+	code := exitOK
+	for i, target := range flags.Args() {
+		if i > 0 {
+			LogInfo("")
+		}
+
+		addr, err := readInfoTarget(target)
+		if err != nil {
+			LogError("%s: Could not read %s: %v.", color.RedString("Error"), target, err)
+			code = exitError
+			continue
+		}
+
+		printAddressInfo(addr, !*hideSecret)
+		if period != nil {
+			printTimePeriodInfo(addr, *period)
+		}
+	}
+
+	return code
+}
+
+// readInfoTarget reads the address from target, which is either a key directory or an
+// onion hostname.
+func readInfoTarget(target string) (*shrek.OnionAddress, error) {
+	if fi, err := os.Stat(target); err == nil && fi.IsDir() {
+		return shrek.ReadOnionAddress(target)
+	}
+
+	return shrek.ParseHostName(target)
+}
+
+func printAddressInfo(addr *shrek.OnionAddress, showSecret bool) {
+	checksum := addr.Checksum()
+	pkSum := sha256.Sum256(addr.PublicKey)
+
+	printField("Hostname", "%s", addr.HostNameString())
+	printField("Version", "%d", shrek.AddressVersion)
+	printField("Checksum", "%s", hex.EncodeToString(checksum[:]))
+	printField("Public key (hex)", "%s", hex.EncodeToString(addr.PublicKey))
+	printField("Public key (base64)", "%s", base64.StdEncoding.EncodeToString(addr.PublicKey))
+	printField("Public key SHA-256", "%s", hex.EncodeToString(pkSum[:]))
+
+	if len(addr.SecretKey) == 0 {
+		printField("Secret key", "%s", color.YellowString("not available"))
+		return
+	}
+
+	skSum := sha256.Sum256(addr.SecretKey)
+	printField("Secret key SHA-256", "%s", hex.EncodeToString(skSum[:]))
+
+	if !showSecret {
+		return
+	}
+
+	key, err := addr.ControlPortKey()
+	if err != nil {
+		printField("Control port key", "%s", color.RedString("%s", trimErr(err)))
+		return
+	}
+	printField("Control port key", "%s", key)
+}
+
+// parseTimePeriod parses the --time-period flag, which is a time period number or
+// "current".
+func parseTimePeriod(v string, length uint64) (shrek.TimePeriod, error) {
+	if v == "current" {
+		return shrek.TimePeriodAt(time.Now(), length), nil
+	}
+
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return shrek.TimePeriod{}, fmt.Errorf("time period '%s' is not a number or \"current\"", v)
+	}
+	if length == 0 {
+		length = shrek.DefaultTimePeriodLength
+	}
+
+	return shrek.TimePeriod{Number: n, Length: length}, nil
+}
+
+func printTimePeriodInfo(addr *shrek.OnionAddress, period shrek.TimePeriod) {
+	printField("Time period", "%d (%s to %s)",
+		period.Number,
+		period.Start().Format(time.RFC3339),
+		period.End().Format(time.RFC3339),
+	)
+
+	blinded, err := addr.BlindedPublicKey(period)
+	if err != nil {
+		printField("Blinded key", "%s", color.RedString("%s", trimErr(err)))
+		return
+	}
+	printField("Blinded key (hex)", "%s", hex.EncodeToString(blinded))
+	printField("Blinded key (base64)", "%s", base64.StdEncoding.EncodeToString(blinded))
+
+	// Neither of these can fail if the blinded key could be computed.
+	subcredential, _ := addr.Subcredential(period)
+	printField("Subcredential", "%s", hex.EncodeToString(subcredential[:]))
+	for replica := uint64(1); replica <= 2; replica++ {
+		idx, _ := addr.HSDirIndex(period, replica)
+		printField(fmt.Sprintf("HSDir index %d", replica), "%s", hex.EncodeToString(idx[:]))
+	}
+}
+
+func printField(name, format string, a ...interface{}) {
+	LogInfo("%s %s", color.CyanString("%-22s", name+":"), fmt.Sprintf(format, a...))
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 )
@@ -9,6 +10,10 @@ import (
 var (
 	LogVerboseEnabled = false
 	LogPrettyEnabled  = false
+
+	// LogOutput is where info and verbose messages are written. It's changed to stderr
+	// when stdout is used for something else, such as streaming an archive.
+	LogOutput io.Writer = os.Stdout
 )
 
 func LogError(format string, a ...interface{}) {
@@ -23,12 +28,12 @@ func LogError(format string, a ...interface{}) {
 }
 
 func LogInfo(format string, a ...interface{}) {
-	_, _ = fmt.Fprintln(os.Stdout, fmt.Sprintf(format, a...))
+	_, _ = fmt.Fprintln(LogOutput, fmt.Sprintf(format, a...))
 }
 
 func LogVerbose(format string, a ...interface{}) {
 	if LogVerboseEnabled {
-		_, _ = fmt.Fprintln(os.Stdout, fmt.Sprintf(format, a...))
+		_, _ = fmt.Fprintln(LogOutput, fmt.Sprintf(format, a...))
 	}
 }
 
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -10,6 +11,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/briandowns/spinner"
@@ -24,6 +26,17 @@ const (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if cmd := findCommand(os.Args[1]); cmd != nil {
+			if cmd.isFilter() {
+				LogError("%s: Running the %s command. To search for addresses that start with %q instead, use: %s -- %s",
+					color.YellowString("Note"), cmd.Name, cmd.Name, filepath.Base(os.Args[0]), cmd.Name,
+				)
+			}
+			os.Exit(cmd.Run(os.Args[2:]))
+		}
+	}
+
 	opts := buildAppOptions()
 	runtime.GOMAXPROCS(opts.NumThreads + 1) // +1 for main proc.
 
@@ -31,18 +44,31 @@ func main() {
 	LogPrettyEnabled = opts.Formatting.UseEnhanced()
 	color.NoColor = !opts.Formatting.UseColors()
 
+	// Keep stdout clean for the archive when it's being streamed there.
+	if opts.OutputArchive == "-" {
+		LogOutput = os.Stderr
+	}
+
+	saver, saverName, closeSaver, err := openSaver(opts)
+	if err != nil {
+		LogError("%s: Could not open address store: %v.", color.RedString("Error"), err)
+		os.Exit(exitUsage)
+	}
 	LogInfo("%sSaving found addresses to %s",
 		Pretty("📁 ", ""),
-		color.YellowString("%s", opts.SaveDirectory),
+		color.YellowString("%s", saverName),
 	)
 	LogInfo("")
 
-	m, err := buildMatcher(opts.Patterns)
+	m, err := buildMatcher(opts.Patterns, opts.SpecFile)
 	if err != nil {
 		LogError("%s: Could not build search filters: %v.", color.RedString("Error"), err)
-		os.Exit(2)
+		os.Exit(exitUsage)
 	}
 
+	// Never start more miners than there are attempts to share between them.
+	budgets := splitBudget(opts.MaxAttempts, opts.NumThreads)
+
 	addrText := color.GreenString("%d", opts.NumAddresses)
 	if opts.NumAddresses == 0 {
 		addrText = color.GreenString("infinite")
@@ -50,47 +76,88 @@ func main() {
 	LogInfo("%sSearching for %s addresses, using %s threads, with %s search filters:",
 		Pretty("🔥 ", ""),
 		addrText,
-		color.GreenString("%d", opts.NumThreads),
+		color.GreenString("%d", len(budgets)),
 		color.GreenString("%d", len(m.Inner)),
 	)
-	defer func() {
-		LogInfo("")
-		LogInfo("%sShrek has finished searching.", Pretty("👍 ", ""))
-	}()
 
 	// Channel to receive onion addresses from miners.
-	addrs := make(chan *shrek.OnionAddress, opts.NumAddresses)
+	addrs := make(chan *shrek.MineResult, opts.NumAddresses)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	if opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	// Stop searching on the first SIGINT/SIGTERM, but still save what's been found. A
+	// second signal forces the program to exit straight away.
+	stats := runStats{start: time.Now()}
+	handleSignals(cancel, &stats)
 
 	// Spin up the miners.
-	wg := runWorkGroup(opts.NumThreads, func(n int) {
-		if err := mineHostNames(ctx, addrs, m); err != nil && !errors.Is(err, ctx.Err()) {
+	wg := runWorkGroup(len(budgets), func(n int) {
+		err := mineHostNames(ctx, n, addrs, m, budgets[n], &stats.attempts)
+
+		switch {
+		case errors.Is(err, shrek.ErrLimitReached):
+			atomic.StoreInt32(&stats.limited, 1)
+		case err != nil && !errors.Is(err, ctx.Err()):
+			atomic.StoreInt32(&stats.failed, 1)
 			LogError("%s: %v.", color.RedString("Error"), err)
 		}
 	})
 
-	// Loop until the requested number of addresses have been mined.
+	// Close the channel once all the miners have stopped, so the loop below knows that
+	// no more addresses are coming.
+	go func() {
+		wg.Wait()
+		close(addrs)
+	}()
+
+	// Loop until the requested number of addresses have been mined, then keep draining
+	// the channel until the miners have stopped. Addresses found after the requested
+	// number are discarded, and their keys wiped.
 	mineForever := opts.NumAddresses == 0
 	ps := newProgressSpinner("   ", time.Millisecond*130)
-	for i := 0; i < opts.NumAddresses || mineForever; i++ {
-		ps.Start()
-		addr := <-addrs
-		hostname := addr.HostNameString()
+	ps.Start()
+	for res := range addrs {
+		if stats.found >= opts.NumAddresses && !mineForever {
+			res.Address.Destroy()
+			continue
+		}
 		ps.Stop()
 
-		LogInfo("%s%s", Pretty("   🔹 ", ""), hostname)
-		if err := shrek.SaveOnionAddress(opts.SaveDirectory, addr); err != nil {
-			LogError("%s: Found .onion but could not save it to file system: %v.",
+		LogInfo("%s%s %s", Pretty("   🔹 ", ""), res.Address.HostNameString(), color.HiBlackString(
+			"(thread %d, %d keys in %s)", res.WorkerID, res.Attempts, res.Elapsed.Round(time.Millisecond),
+		))
+		if err := saver.Save(res.Address); err != nil {
+			LogError("%s: Found .onion but could not save it to the store: %v.",
 				color.RedString("Error"),
 				err,
 			)
 		}
+		// The store has its own copy of the key now.
+		res.Address.Destroy()
+
+		if stats.found++; stats.found >= opts.NumAddresses && !mineForever {
+			cancel()
+			continue
+		}
+		ps.Start()
+	}
+	ps.Stop()
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		atomic.StoreInt32(&stats.limited, 1)
 	}
 
-	cancel()
-	wg.Wait()
+	if err := closeSaver(); err != nil {
+		LogError("%s: Could not finish writing addresses: %v.", color.RedString("Error"), err)
+		atomic.StoreInt32(&stats.failed, 1)
+	}
+
+	os.Exit(printSummary(opts, &stats))
 }
 
 func buildAppOptions() appOptions {
@@ -98,6 +165,14 @@ func buildAppOptions() appOptions {
 
 	pflag.IntVarP(&opts.NumAddresses, "onions", "n", 0, "`num`ber of onion addresses to generate, 0 = infinite (default = 1)")
 	pflag.StringVarP(&opts.SaveDirectory, "save-dir", "d", "", "`dir`ectory to save addresses in (default = cwd)")
+	pflag.StringVarP(&opts.Store, "store", "", "", "where to save addresses, as `kind`[:path] where kind is dir, tar, or zip (default = dir)")
+	pflag.StringVarP(&opts.OutputArchive, "output-archive", "", "", "stream found addresses as a tar archive to this `file`, or - for stdout")
+	pflag.BoolVarP(&opts.Torrc, "torrc", "", false, "also save a torrc.snippet file with each address")
+	pflag.StringArrayVarP(&opts.TorrcPorts, "torrc-port", "", nil, "`port` for the torrc snippet, as virtual[:target] (repeatable, default = 80)")
+	pflag.StringVarP(&opts.TorrcDir, "torrc-dir", "", "", "`dir`ectory the torrc snippet expects the keys to be copied into (default = /var/lib/tor)")
+	pflag.StringVarP(&opts.SpecFile, "spec", "", "", "JSON `file` containing a matcher spec to search for")
+	pflag.DurationVarP(&opts.Timeout, "timeout", "", 0, "stop searching after this `duration`, e.g. 90s or 2h (default = no limit)")
+	pflag.Uint64VarP(&opts.MaxAttempts, "max-attempts", "", 0, "stop searching after trying this `num`ber of keys (default = no limit)")
 	pflag.IntVarP(&opts.NumThreads, "threads", "t", 0, "`num`ber of threads to use (default = all CPU cores)")
 	pflag.VarP(&opts.Formatting, "format", "", "what `kind` of formatting to use (basic, colored, enhanced, default = all)")
 
@@ -109,9 +184,27 @@ func buildAppOptions() appOptions {
 	pflag.Usage = func() {
 		LogError("Usage:")
 		LogError("  %s [options] filter [more-filters...]", filepath.Base(os.Args[0]))
+		LogError("  %s command [options] [args...]", filepath.Base(os.Args[0]))
+		LogError("")
+		printCommands()
+		LogError("")
+		LogError("FILTERS")
+		LogError("  start[:end]       address starts with start and ends with end")
+		LogError("  repeat:N          address starts with N identical chars")
+		LogError("  palindrome:N      address starts with an N char palindrome")
+		LogError("  alpha:N           first N chars of address are letters only")
+		LogError("")
+		LogError("  Filters that are also the name of a command must come after --, e.g.:")
+		LogError("    %s -- bench", filepath.Base(os.Args[0]))
 		LogError("")
 		LogError("OPTIONS")
 		pflag.PrintDefaults()
+		LogError("")
+		LogError("EXIT CODES")
+		LogError("  %d  all requested addresses were found", exitOK)
+		LogError("  %d  an error occurred", exitError)
+		LogError("  %d  the command line was not valid", exitUsage)
+		LogError("  %d  the search was stopped by --timeout or --max-attempts", exitLimit)
 	}
 	pflag.Parse()
 
@@ -125,15 +218,15 @@ func buildAppOptions() appOptions {
 
 	if version {
 		LogInfo("%s %s, os: %s, arch: %s", appName, appVersion, runtime.GOOS, runtime.GOARCH)
-		os.Exit(0)
+		os.Exit(exitOK)
 	} else if help {
 		pflag.Usage()
-		os.Exit(0)
-	} else if pflag.NArg() < 1 {
+		os.Exit(exitOK)
+	} else if pflag.NArg() < 1 && opts.SpecFile == "" {
 		LogError("No filters provided.")
 		LogError("")
 		pflag.Usage()
-		os.Exit(2)
+		os.Exit(exitUsage)
 	}
 
 	// Set runtime to use number of threads requested.
@@ -154,7 +247,7 @@ func buildAppOptions() appOptions {
 				color.RedString("Error"),
 				err,
 			)
-			os.Exit(1)
+			os.Exit(exitError)
 		}
 		opts.SaveDirectory = absd
 	}
@@ -165,47 +258,82 @@ func buildAppOptions() appOptions {
 	return opts
 }
 
-func buildMatcher(args []string) (shrek.MultiMatcher, error) {
-	var mm shrek.MultiMatcher
-	var inner []shrek.StartEndMatcher
-
-	for _, pattern := range args {
-		parts := strings.Split(pattern, ":")
-
-		switch len(parts) {
-		case 1:
-			start := parts[0]
-			m := shrek.StartEndMatcher{
-				Start: []byte(start),
-				End:   nil,
-			}
-			if err := m.Validate(); err != nil {
-				return mm, fmt.Errorf(
-					"pattern '%s' is not valid: %w", color.YellowString("%s", pattern), err,
-				)
-			}
-			inner = append(inner, m)
-		case 2:
-			start, end := parts[0], parts[1]
-			m := shrek.StartEndMatcher{
-				Start: []byte(start),
-				End:   []byte(end),
-			}
-			if err := m.Validate(); err != nil {
-				return mm, fmt.Errorf(
-					"pattern '%s' is not valid: %w", color.YellowString("%s", pattern), err,
+func buildMatcher(args []string, specFile string) (shrek.MultiMatcher, error) {
+	mm, err := shrek.ParsePatterns(args)
+	if err != nil {
+		var pe *shrek.PatternError
+		if errors.As(err, &pe) {
+			hint := ""
+			if pe.Part == shrek.PatternPartEnd && len(pe.Suggestions) > 0 {
+				start := strings.SplitN(pe.Pattern, ":", 2)[0]
+				hint = fmt.Sprintf(" (did you mean '%s'?)",
+					color.YellowString("%s:%s", start, pe.Suggestions[0]),
 				)
 			}
-			inner = append(inner, m)
-		default:
-			return mm, fmt.Errorf(
-				"pattern '%s' is not a valid syntax", color.YellowString("%s", pattern),
+			return mm, fmt.Errorf("pattern '%s' is not valid: %s%s",
+				color.YellowString("%s", pe.Pattern), pe.Reason, hint,
 			)
 		}
+		return mm, err
+	}
+
+	if specFile != "" {
+		m, err := readSpecFile(specFile)
+		if err != nil {
+			return mm, err
+		}
+		mm.Inner = append(mm.Inner, m)
 	}
 
 	LogVerbose("%sLooking for addresses that match any of these conditions:", Pretty("🔎 ", ""))
-	for _, m := range inner {
+	for _, m := range mm.Inner {
+		LogVerbose("%s%s", Pretty("   🔸 ", " - "), describeMatcher(m))
+	}
+	LogVerbose("")
+
+	// Warn about end parts that depend on the checksum, as they are much slower to find.
+	for _, m := range mm.Inner {
+		sem, ok := m.(shrek.StartEndMatcher)
+		if !ok {
+			continue
+		}
+
+		if d := sem.Difficulty(); d.ChecksumBits > 0 {
+			LogVerbose("%sThe end part '%s' depends on %s checksum bits, so about %s keys must be fully encoded per match.",
+				Pretty("⚠️  ", "Warning: "),
+				color.YellowString("%s", sem.End),
+				color.YellowString("%d", d.ChecksumBits),
+				color.YellowString("%.0f", d.ExpectedExactEncodings()),
+			)
+			LogVerbose("")
+		}
+	}
+
+	return mm, nil
+}
+
+func readSpecFile(name string) (shrek.Matcher, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("could not read spec file: %w", err)
+	}
+
+	var spec shrek.MatcherSpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("spec file '%s' is not valid: %w", color.YellowString("%s", name), err)
+	}
+
+	m, err := spec.Build()
+	if err != nil {
+		return nil, fmt.Errorf("spec file '%s' is not valid: %w", color.YellowString("%s", name), err)
+	}
+
+	return m, nil
+}
+
+func describeMatcher(m shrek.Matcher) string {
+	switch m := m.(type) {
+	case shrek.StartEndMatcher:
 		startsWith := fmt.Sprintf("'%s'", color.YellowString("%s", m.Start))
 		endsWith := fmt.Sprintf("'%s'", color.YellowString("%s", m.End))
 		if len(m.Start) == 0 {
@@ -215,17 +343,42 @@ func buildMatcher(args []string) (shrek.MultiMatcher, error) {
 			endsWith = color.YellowString("anything")
 		}
 
-		LogVerbose("%sAn address that starts with %s and ends with %s",
-			Pretty("   🔸 ", " - "),
-			startsWith,
-			endsWith,
+		return fmt.Sprintf("An address that starts with %s and ends with %s", startsWith, endsWith)
+	case shrek.RepeatMatcher:
+		return fmt.Sprintf("An address that starts with %s identical chars",
+			color.YellowString("%d", m.Length),
 		)
-
-		mm.Inner = append(mm.Inner, m)
+	case shrek.PalindromeMatcher:
+		return fmt.Sprintf("An address that starts with a %s char palindrome",
+			color.YellowString("%d", m.Length),
+		)
+	case shrek.AlphaMatcher:
+		return fmt.Sprintf("An address where the first %s chars are letters only",
+			color.YellowString("%d", m.Length),
+		)
+	case shrek.ContainsMatcher:
+		return fmt.Sprintf("An address that contains '%s'", color.YellowString("%s", m.Text))
+	case shrek.RegexMatcher:
+		return fmt.Sprintf("An address that matches the regex '%s'", color.YellowString("%s", m.Regexp))
+	case shrek.ExcludeMatcher:
+		excl := make([]string, 0, len(m.Exclude))
+		for _, em := range m.Exclude {
+			excl = append(excl, describeMatcher(em))
+		}
+		return fmt.Sprintf("%s, excluding: %s", describeMatcher(m.Inner), strings.Join(excl, "; "))
+	case shrek.MultiMatcher:
+		inner := make([]string, 0, len(m.Inner))
+		for _, im := range m.Inner {
+			inner = append(inner, describeMatcher(im))
+		}
+		sep := " OR "
+		if m.All {
+			sep = " AND "
+		}
+		return "(" + strings.Join(inner, sep) + ")"
+	default:
+		return fmt.Sprintf("An address that matches %s", color.YellowString("%T", m))
 	}
-	LogVerbose("")
-
-	return mm, nil
 }
 
 func runWorkGroup(n int, fn func(n int)) *sync.WaitGroup {
@@ -242,22 +395,62 @@ func runWorkGroup(n int, fn func(n int)) *sync.WaitGroup {
 	return &wg
 }
 
-func mineHostNames(ctx context.Context, ch chan<- *shrek.OnionAddress, m shrek.Matcher) error {
+func mineHostNames(
+	ctx context.Context, id int, ch chan<- *shrek.MineResult, m shrek.Matcher, maxAttempts uint64, attempts *uint64,
+) error {
+	remaining := maxAttempts
+
 	for ctx.Err() == nil {
-		addr, err := shrek.MineOnionHostName(ctx, nil, m)
+		if maxAttempts > 0 && remaining == 0 {
+			return shrek.ErrLimitReached
+		}
+
+		var res shrek.MineResult
+		_, err := shrek.MineOnionHostNameWithOptions(ctx, m,
+			shrek.WithWorkerID(id),
+			shrek.WithResult(&res),
+			shrek.WithMaxAttempts(remaining),
+		)
+		atomic.AddUint64(attempts, res.Attempts)
+		if maxAttempts > 0 {
+			remaining -= res.Attempts
+		}
 		if err != nil {
 			return err
 		}
 
-		select {
-		case ch <- addr:
-		case <-ctx.Done():
-		}
+		// Always hand over the address, even if the context is done, so that an address
+		// found just as the search is stopped still gets saved.
+		ch <- &res
 	}
 
 	return ctx.Err()
 }
 
+// splitBudget divides the total number of attempts evenly between the workers, and
+// returns the share of each worker that should be started. Zero means there's no limit,
+// so if there are fewer attempts than workers, only as many workers as there are attempts
+// get a share; a share of zero would let a worker run forever.
+func splitBudget(total uint64, workers int) []uint64 {
+	if total > 0 && total < uint64(workers) {
+		workers = int(total)
+	}
+
+	shares := make([]uint64, workers)
+	if total == 0 {
+		return shares
+	}
+
+	for n := range shares {
+		shares[n] = total / uint64(workers)
+		if uint64(n) < total%uint64(workers) {
+			shares[n]++
+		}
+	}
+
+	return shares
+}
+
 func newProgressSpinner(prefix string, speed time.Duration) *spinner.Spinner {
 	s := spinner.New(spinner.CharSets[14], speed)
 	s.HideCursor = true
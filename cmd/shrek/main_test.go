@@ -0,0 +1,45 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitBudget(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		Name    string
+		Total   uint64
+		Workers int
+		Want    []uint64
+	}{
+		{Name: "no limit", Total: 0, Workers: 3, Want: []uint64{0, 0, 0}},
+		{Name: "even", Total: 9, Workers: 3, Want: []uint64{3, 3, 3}},
+		{Name: "remainder", Total: 11, Workers: 3, Want: []uint64{4, 4, 3}},
+		{Name: "equal", Total: 4, Workers: 4, Want: []uint64{1, 1, 1, 1}},
+		{Name: "fewer attempts than workers", Total: 2, Workers: 8, Want: []uint64{1, 1}},
+		{Name: "one attempt", Total: 1, Workers: 8, Want: []uint64{1}},
+	}
+
+	for _, tc := range table {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			got := splitBudget(tc.Total, tc.Workers)
+			if !reflect.DeepEqual(got, tc.Want) {
+				t.Errorf("unexpected shares, got: %v, wanted: %v", got, tc.Want)
+			}
+
+			var sum uint64
+			for _, share := range got {
+				sum += share
+			}
+			if sum != tc.Total {
+				t.Errorf("shares add up to %d, wanted: %d", sum, tc.Total)
+			}
+		})
+	}
+}
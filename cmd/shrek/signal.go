@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+)
+
+// handleSignals cancels the search when the first SIGINT or SIGTERM is received, which
+// lets the miners and the save loop finish cleanly. If a second signal is received
+// before the program exits, it exits immediately.
+func handleSignals(cancel context.CancelFunc, stats *runStats) {
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-sigs
+		atomic.StoreInt32(&stats.interrupted, 1)
+		LogError("")
+		LogError("%sStopping, waiting for found addresses to be saved. Press Ctrl-C again to force exit.",
+			Pretty("✋ ", ""),
+		)
+		cancel()
+
+		<-sigs
+		LogError("Forced exit, some found addresses might not have been saved.")
+		os.Exit(exitInterrupted)
+	}()
+}
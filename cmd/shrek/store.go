@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/innix/shrek"
+)
+
+// openStore opens the address store described by the --store flag, which has the form
+// kind[:path]. The path of a dir store defaults to the save dir; the path of an archive
+// store defaults to a file named onions.tar or onions.zip inside the save dir.
+func openStore(spec, saveDir string, saveOpts ...shrek.SaveOption) (shrek.AddressStore, string, error) {
+	kind, name := spec, ""
+	if i := strings.IndexByte(spec, ':'); i >= 0 {
+		kind, name = spec[:i], spec[i+1:]
+	}
+
+	switch kind {
+	case "", "dir":
+		if name == "" {
+			name = saveDir
+		}
+		return shrek.DirStore{Dir: name, SaveOptions: saveOpts}, name, nil
+	case "tar", "zip":
+		if len(saveOpts) > 0 {
+			return nil, "", errors.New("--torrc can only be used with the dir store")
+		}
+		if name == "" {
+			name = filepath.Join(saveDir, "onions."+kind)
+		}
+		s, err := shrek.OpenArchiveStore(name, shrek.ArchiveFormat(kind))
+		if err != nil {
+			return nil, "", err
+		}
+		return s, name, nil
+	default:
+		return nil, "", fmt.Errorf("unknown store kind '%s', expected dir, tar, or zip", kind)
+	}
+}
+
+// addressSaver is the part of shrek.AddressStore that the search needs, so that found
+// addresses can also be streamed to an archive that can't be read back.
+type addressSaver interface {
+	Save(addr *shrek.OnionAddress) error
+}
+
+// openSaver opens where found addresses are saved to: the --output-archive if one was
+// given, otherwise the --store. The returned function must be called once the search is
+// over, to finish writing the archive.
+func openSaver(opts appOptions) (addressSaver, string, func() error, error) {
+	noop := func() error { return nil }
+
+	var saveOpts []shrek.SaveOption
+	if opts.Torrc {
+		cfg, err := buildTorrcConfig(opts.TorrcPorts, opts.TorrcDir)
+		if err != nil {
+			return nil, "", noop, err
+		}
+		saveOpts = append(saveOpts, shrek.WithTorrcSnippet(cfg))
+	}
+
+	if opts.OutputArchive == "" {
+		s, name, err := openStore(opts.Store, opts.SaveDirectory, saveOpts...)
+		return s, name, noop, err
+	}
+	if opts.Store != "" {
+		return nil, "", noop, errors.New("--store and --output-archive can't be used together")
+	}
+	if opts.Torrc {
+		return nil, "", noop, errors.New("--torrc can't be used with --output-archive")
+	}
+
+	if opts.OutputArchive == "-" {
+		if fi, err := os.Stdout.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+			return nil, "", noop, errors.New("refusing to write an archive to a terminal, redirect stdout to a file")
+		}
+		tw := shrek.NewTarWriter(os.Stdout)
+		return tw, "stdout", tw.Close, nil
+	}
+
+	// Like shrek.SaveOnionAddress, never overwrite an existing file.
+	f, err := os.OpenFile(opts.OutputArchive, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, "", noop, err
+	}
+	tw := shrek.NewTarWriter(f)
+	closeFn := func() error {
+		if err := tw.Close(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+
+	return tw, opts.OutputArchive, closeFn, nil
+}
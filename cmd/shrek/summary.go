@@ -0,0 +1,74 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+	exitLimit = 3
+
+	// exitInterrupted follows the shell convention of 128 + SIGINT.
+	exitInterrupted = 130
+)
+
+type runStats struct {
+	start time.Time
+	found int
+
+	// Accessed by the miners concurrently, so only use atomic operations on these.
+	attempts    uint64
+	limited     int32
+	failed      int32
+	interrupted int32
+}
+
+// printSummary prints a summary of the search and returns the exit code for it.
+func printSummary(opts appOptions, stats *runStats) int {
+	elapsed := time.Since(stats.start)
+	attempts := atomic.LoadUint64(&stats.attempts)
+
+	rate := 0.0
+	if secs := elapsed.Seconds(); secs > 0 {
+		rate = float64(attempts) / secs
+	}
+
+	wanted := color.GreenString("infinite")
+	if opts.NumAddresses > 0 {
+		wanted = color.GreenString("%d", opts.NumAddresses)
+	}
+
+	LogInfo("")
+	LogInfo("%sFound %s of %s addresses, tried %s keys in %s (%s keys/sec).",
+		Pretty("📊 ", ""),
+		color.GreenString("%d", stats.found),
+		wanted,
+		color.GreenString("%d", attempts),
+		color.GreenString("%s", elapsed.Round(time.Millisecond)),
+		color.GreenString("%.0f", rate),
+	)
+
+	code := exitOK
+	switch {
+	case opts.NumAddresses > 0 && stats.found >= opts.NumAddresses:
+		LogInfo("%sShrek has finished searching.", Pretty("👍 ", ""))
+	case atomic.LoadInt32(&stats.interrupted) != 0:
+		LogInfo("%sShrek stopped searching because it was interrupted.", Pretty("✋ ", ""))
+		code = exitInterrupted
+	case atomic.LoadInt32(&stats.failed) != 0:
+		LogInfo("%sShrek stopped searching because of an error.", Pretty("💥 ", ""))
+		code = exitError
+	case atomic.LoadInt32(&stats.limited) != 0:
+		LogInfo("%sShrek stopped searching because it reached the search limit.", Pretty("⏱️  ", ""))
+		code = exitLimit
+	default:
+		LogInfo("%sShrek has finished searching.", Pretty("👍 ", ""))
+	}
+
+	return code
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/innix/shrek"
+)
+
+func runTorrc(args []string) int {
+	cmd := findCommand("torrc")
+	flags, f := newCommandFlagSet(cmd)
+	ports := flags.StringArrayP("port", "p", nil, "`port` to forward, as virtual[:target], e.g. 80:8080 (repeatable, default = 80)")
+	torDir := flags.StringP("tor-dir", "", "", "`dir`ectory that the key directories are copied into on the Tor host (default = /var/lib/tor)")
+	output := flags.StringP("output", "o", "", "`file` to write the torrc to (default = stdout)")
+	parseCommandFlags(flags, f, args)
+
+	if flags.NArg() < 1 {
+		LogError("No directories provided.")
+		LogError("")
+		flags.Usage()
+		return exitUsage
+	}
+
+	cfg, err := buildTorrcConfig(*ports, *torDir)
+	if err != nil {
+		LogError("%s: %v.", color.RedString("Error"), err)
+		return exitUsage
+	}
+
+	var addrs []*shrek.OnionAddress
+	for _, root := range flags.Args() {
+		dirs, err := findKeyDirs(root)
+		if err != nil {
+			LogError("%s: Could not search directory: %v.", color.RedString("Error"), err)
+			return exitError
+		}
+
+		for _, dir := range dirs {
+			addr, err := shrek.ReadOnionAddress(dir)
+			if err != nil {
+				LogError("%s: Could not read key directory '%s': %v.", color.RedString("Error"), dir, err)
+				return exitError
+			}
+			addrs = append(addrs, addr)
+		}
+	}
+
+	if len(addrs) == 0 {
+		LogError("%s: No key directories found.", color.RedString("Error"))
+		return exitError
+	}
+
+	torrc, err := shrek.BuildTorrc(cfg, addrs...)
+	if err != nil {
+		LogError("%s: Could not build torrc: %v.", color.RedString("Error"), err)
+		return exitError
+	}
+
+	if *output == "" || *output == "-" {
+		_, err = os.Stdout.Write(torrc)
+	} else {
+		err = os.WriteFile(*output, torrc, 0o600)
+	}
+	if err != nil {
+		LogError("%s: Could not write torrc: %v.", color.RedString("Error"), err)
+		return exitError
+	}
+
+	return exitOK
+}
+
+// buildTorrcConfig parses the ports given on the command line into a torrc config.
+func buildTorrcConfig(ports []string, torDir string) (shrek.TorrcConfig, error) {
+	cfg := shrek.TorrcConfig{Dir: torDir}
+	for _, p := range ports {
+		hsp, err := shrek.ParseHiddenServicePort(p)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Ports = append(cfg.Ports, hsp)
+	}
+
+	return cfg, nil
+}
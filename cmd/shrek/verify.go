@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/innix/shrek"
+)
+
+// The permissions that shrek.SaveOnionAddress creates directories and files with.
+const (
+	wantDirMode  = fs.FileMode(0o700)
+	wantFileMode = fs.FileMode(0o600)
+)
+
+func runVerify(args []string) int {
+	cmd := findCommand("verify")
+	flags, f := newCommandFlagSet(cmd)
+	parseCommandFlags(flags, f, args)
+
+	if flags.NArg() < 1 {
+		LogError("No directories provided.")
+		LogError("")
+		flags.Usage()
+		return exitUsage
+	}
+
+	var dirs []string
+	for _, root := range flags.Args() {
+		found, err := findKeyDirs(root)
+		if err != nil {
+			LogError("%s: Could not search directory: %v.", color.RedString("Error"), err)
+			return exitError
+		}
+		dirs = append(dirs, found...)
+	}
+
+	if len(dirs) == 0 {
+		LogError("%s: No key directories found.", color.RedString("Error"))
+		return exitError
+	}
+
+	failed := 0
+	for _, dir := range dirs {
+		problems := verifyKeyDir(dir)
+		if len(problems) == 0 {
+			LogInfo("%s %s", color.GreenString("PASS"), dir)
+			continue
+		}
+
+		failed++
+		LogInfo("%s %s", color.RedString("FAIL"), dir)
+		for _, p := range problems {
+			LogInfo("%s%s", Pretty("   🔸 ", "   - "), p)
+		}
+	}
+
+	LogInfo("")
+	LogInfo("%s of %s key directories passed verification.",
+		color.GreenString("%d", len(dirs)-failed),
+		color.GreenString("%d", len(dirs)),
+	)
+
+	if failed > 0 {
+		return exitError
+	}
+	return exitOK
+}
+
+// findKeyDirs walks root and returns every directory that contains at least one of the
+// files written by shrek.SaveOnionAddress.
+func findKeyDirs(root string) ([]string, error) {
+	var dirs []string
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+
+		for _, name := range []string{shrek.PublicKeyFileName, shrek.SecretKeyFileName, shrek.HostNameFileName} {
+			if _, err := os.Lstat(filepath.Join(path, name)); err == nil {
+				dirs = append(dirs, path)
+				break
+			}
+		}
+
+		return nil
+	})
+
+	return dirs, err
+}
+
+// verifyKeyDir checks a single key directory and returns a description of every problem
+// found. It returns nil if the directory is fine.
+func verifyKeyDir(dir string) []string {
+	var problems []string
+
+	// Check keys. ReadOnionAddress checks the file headers, the key lengths, and that the
+	// public key can be derived from the secret key.
+	addr, err := shrek.ReadOnionAddress(dir)
+	if err != nil {
+		problems = append(problems, trimErr(err))
+	}
+
+	// Check hostname file agrees with the keys. Tor adds a trailing newline, so allow it.
+	hostname, err := os.ReadFile(filepath.Join(dir, shrek.HostNameFileName))
+	switch {
+	case err != nil:
+		problems = append(problems, fmt.Sprintf("could not read hostname file: %s", trimErr(err)))
+	case addr != nil:
+		if got, want := string(bytes.TrimSpace(hostname)), addr.HostNameString(); got != want {
+			problems = append(problems, fmt.Sprintf("hostname file contains %q, but keys are for %q", got, want))
+		}
+	}
+
+	// File permissions aren't meaningful on Windows.
+	if runtime.GOOS == "windows" {
+		return problems
+	}
+
+	if p := checkMode(dir, wantDirMode); p != "" {
+		problems = append(problems, p)
+	}
+	for _, name := range []string{shrek.PublicKeyFileName, shrek.SecretKeyFileName, shrek.HostNameFileName} {
+		if p := checkMode(filepath.Join(dir, name), wantFileMode); p != "" {
+			problems = append(problems, p)
+		}
+	}
+
+	return problems
+}
+
+func checkMode(path string, want fs.FileMode) string {
+	fi, err := os.Stat(path)
+	if err != nil {
+		// Missing files are already reported by the other checks.
+		return ""
+	}
+
+	if got := fi.Mode().Perm(); got != want {
+		return fmt.Sprintf("%s has permissions %04o, expected %04o", filepath.Base(path), got, want)
+	}
+
+	return ""
+}
+
+// trimErr returns the error message without the "shrek: " prefix.
+func trimErr(err error) string {
+	return strings.ReplaceAll(err.Error(), "shrek: ", "")
+}
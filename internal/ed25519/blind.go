@@ -0,0 +1,49 @@
+package ed25519
+
+import (
+	"fmt"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+)
+
+// BlindingParamSize is the size, in bytes, of the blinding parameter given to
+// BlindPublicKey.
+const BlindingParamSize = 32
+
+// BlindPublicKey blinds the public key with the 32 byte parameter, as described in
+// appendix A.2 of Tor's rend-spec-v3. The parameter is clamped the same way a private
+// key is, then the public key point is multiplied by it.
+func BlindPublicKey(pk PublicKey, param []byte) (PublicKey, error) {
+	if l := len(pk); l != PublicKeySize {
+		return nil, fmt.Errorf("ed25519: bad public key length: %d", l)
+	}
+	if l := len(param); l != BlindingParamSize {
+		return nil, fmt.Errorf("ed25519: bad blinding param length: %d", l)
+	}
+
+	var h [BlindingParamSize]byte
+	copy(h[:], param)
+	h[0] &= 248
+	h[31] &= 63
+	h[31] |= 64
+
+	sc, err := scalar.NewFromBytesModOrder(h[:])
+	if err != nil {
+		return nil, fmt.Errorf("ed25519: could not parse scalar from blinding param: %w", err)
+	}
+
+	cpt, err := curve.NewCompressedEdwardsYFromBytes(pk)
+	if err != nil {
+		return nil, fmt.Errorf("ed25519: could not parse point from public key: %w", err)
+	}
+	pt := curve.NewEdwardsPoint()
+	if _, err := pt.SetCompressedY(cpt); err != nil {
+		return nil, fmt.Errorf("ed25519: could not decompress point from public key: %w", err)
+	}
+
+	var blinded curve.CompressedEdwardsY
+	blinded.SetEdwardsPoint(pt.Mul(pt, sc))
+
+	return blinded[:], nil
+}
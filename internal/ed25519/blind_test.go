@@ -0,0 +1,34 @@
+package ed25519_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/innix/shrek/internal/ed25519"
+)
+
+// TestBlindPublicKey uses a known-answer vector that was generated with Tor, from the
+// key_blinding_testvec test of arti's tor-hscrypto crate. The blinding param is the hash
+// before it's clamped, so the clamping is checked too.
+func TestBlindPublicKey(t *testing.T) {
+	t.Parallel()
+
+	pk, _ := hex.DecodeString("833990b085c1a688c1d4c8b1f6b56afaf5a2eca674449e1d704f83765ccb7bc6")
+	param, _ := hex.DecodeString("379e50db31fee6775abd0af6fb7c371e060308f4f847db09fe4cfe13af602287")
+	want := "3a50bf210e8f9ee955ae0014f7a6917fb65ebf098a86305abb508d1a7291b6d5"
+
+	got, err := ed25519.BlindPublicKey(pk, param)
+	if err != nil {
+		t.Fatalf("could not blind public key: %v", err)
+	}
+	if hex.EncodeToString(got) != want {
+		t.Errorf("unexpected blinded key, got: %x, wanted: %s", got, want)
+	}
+
+	if _, err := ed25519.BlindPublicKey(pk, param[1:]); err == nil {
+		t.Errorf("expected error blinding with a param of the wrong length")
+	}
+	if _, err := ed25519.BlindPublicKey(pk[1:], param); err == nil {
+		t.Errorf("expected error blinding a public key with the wrong length")
+	}
+}
@@ -58,13 +58,38 @@ func GenerateKey(rand io.Reader) (*KeyPair, error) {
 	}
 
 	seed := make([]byte, SeedSize)
+	defer Wipe(seed)
 	if _, err := io.ReadFull(rand, seed); err != nil {
 		return nil, fmt.Errorf("ed25519: could not read seed: %w", err)
 	}
 
+	return NewKeyFromSeed(seed)
+}
+
+// NewKeyFromSeed derives a key pair from a 32 byte seed, the same way as standard
+// Ed25519 implementations do. The private key is stored in its 64 byte expanded form,
+// which the seed can't be recovered from.
+func NewKeyFromSeed(seed []byte) (*KeyPair, error) {
+	if l := len(seed); l != SeedSize {
+		return nil, fmt.Errorf("ed25519: bad seed length: %d", l)
+	}
+
 	sk := make([]byte, PrivateKeySize)
 	newKeyFromSeed(sk, seed)
 
+	return NewKeyPair(sk)
+}
+
+// NewKeyPair returns a key pair for the 64 byte expanded private key, computing the
+// public key from it.
+func NewKeyPair(sk PrivateKey) (*KeyPair, error) {
+	if l := len(sk); l != PrivateKeySize {
+		return nil, fmt.Errorf("ed25519: bad private key length: %d", l)
+	}
+	if !isClamped(sk) {
+		return nil, errors.New("ed25519: private key is not clamped")
+	}
+
 	// Private key does not contain the public key in this implementation, so we
 	// need to compute it instead.
 	pk, err := getPublicKeyFromPrivateKey(sk)
@@ -86,6 +111,7 @@ func newKeyFromSeed(sk, seed []byte) {
 	digest := sha512.Sum512(seed)
 	clampSecretKey(&digest)
 	copy(sk, digest[:])
+	Wipe(digest[:])
 }
 
 func getPublicKeyFromPrivateKey(sk []byte) ([]byte, error) {
@@ -104,8 +130,20 @@ func getPublicKeyFromPrivateKey(sk []byte) ([]byte, error) {
 	return pk[:], nil
 }
 
+func isClamped(sk []byte) bool {
+	return (sk[0]&248) == sk[0] && ((sk[31]&63)|64) == sk[31]
+}
+
 func clampSecretKey(sk *[64]byte) {
 	sk[0] &= 248
 	sk[31] &= 63
 	sk[31] |= 64
 }
+
+// Wipe overwrites b with zeros, so that secret key material doesn't linger in memory
+// after it's no longer needed.
+func Wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
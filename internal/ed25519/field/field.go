@@ -0,0 +1,258 @@
+// Package field implements arithmetic in GF(2^255-19), the field that Ed25519 is
+// defined over, for the key iterator's hot loop.
+//
+// Elements are stored as 4 64-bit limbs and are only partially reduced: any value below
+// 2^256 is allowed, and it's only fully reduced by Bytes. Multiplication and squaring
+// have hand-written assembly on amd64 CPUs with the BMI2 and ADX extensions; everywhere
+// else, and when built with the purego tag, they're written in plain Go.
+package field
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// Element is an element of GF(2^255-19). The zero value is zero.
+type Element [4]uint64
+
+// mask63 is the lower 255 bits of the top limb.
+const mask63 = 1<<63 - 1
+
+// One sets v to 1, and returns v.
+func (v *Element) One() *Element {
+	*v = Element{1, 0, 0, 0}
+	return v
+}
+
+// SetBytes sets v to the 32 byte little-endian encoding x, and returns v. As in
+// RFC 8032, the top bit of x is ignored.
+func (v *Element) SetBytes(x *[32]byte) *Element {
+	v[0] = binary.LittleEndian.Uint64(x[0:])
+	v[1] = binary.LittleEndian.Uint64(x[8:])
+	v[2] = binary.LittleEndian.Uint64(x[16:])
+	v[3] = binary.LittleEndian.Uint64(x[24:]) & mask63
+
+	return v
+}
+
+// Bytes writes the canonical 32 byte little-endian encoding of v to out.
+func (v *Element) Bytes(out *[32]byte) {
+	r := *v
+	r.reduce()
+
+	binary.LittleEndian.PutUint64(out[0:], r[0])
+	binary.LittleEndian.PutUint64(out[8:], r[1])
+	binary.LittleEndian.PutUint64(out[16:], r[2])
+	binary.LittleEndian.PutUint64(out[24:], r[3])
+}
+
+// IsNegative returns 1 if v is negative, i.e. its canonical encoding is odd, and 0
+// otherwise.
+func (v *Element) IsNegative() uint64 {
+	r := *v
+	r.reduce()
+
+	return r[0] & 1
+}
+
+// Equal returns 1 if v and u are equal, and 0 otherwise.
+func (v *Element) Equal(u *Element) uint64 {
+	a, b := *v, *u
+	a.reduce()
+	b.reduce()
+
+	d := (a[0] ^ b[0]) | (a[1] ^ b[1]) | (a[2] ^ b[2]) | (a[3] ^ b[3])
+	return 1 ^ ((d | -d) >> 63)
+}
+
+// reduce fully reduces v mod p.
+func (v *Element) reduce() {
+	// 2^255 = 19 mod p, so fold the top bit back in. Afterwards v < 2^255 + 19.
+	var c uint64
+	top := v[3] >> 63
+	v[3] &= mask63
+	v[0], c = bits.Add64(v[0], top*19, 0)
+	v[1], c = bits.Add64(v[1], 0, c)
+	v[2], c = bits.Add64(v[2], 0, c)
+	v[3] += c
+
+	// v >= p exactly when v + 19 >= 2^255, in which case v - p is v + 19 - 2^255.
+	var t Element
+	t[0], c = bits.Add64(v[0], 19, 0)
+	t[1], c = bits.Add64(v[1], 0, c)
+	t[2], c = bits.Add64(v[2], 0, c)
+	t[3] = v[3] + c
+
+	mask := -(t[3] >> 63)
+	t[3] &= mask63
+	for i := range v {
+		v[i] = (t[i] & mask) | (v[i] &^ mask)
+	}
+}
+
+// Add sets v = a + b, and returns v.
+func (v *Element) Add(a, b *Element) *Element {
+	var c uint64
+	v[0], c = bits.Add64(a[0], b[0], 0)
+	v[1], c = bits.Add64(a[1], b[1], c)
+	v[2], c = bits.Add64(a[2], b[2], c)
+	v[3], c = bits.Add64(a[3], b[3], c)
+
+	// 2^256 = 38 mod p. If adding it back carries too, v is now below 38, so adding it
+	// a second time can't.
+	v[0], c = bits.Add64(v[0], c*38, 0)
+	v[1], c = bits.Add64(v[1], 0, c)
+	v[2], c = bits.Add64(v[2], 0, c)
+	v[3], c = bits.Add64(v[3], 0, c)
+	v[0] += c * 38
+
+	return v
+}
+
+// Sub sets v = a - b, and returns v.
+func (v *Element) Sub(a, b *Element) *Element {
+	var c uint64
+	v[0], c = bits.Sub64(a[0], b[0], 0)
+	v[1], c = bits.Sub64(a[1], b[1], c)
+	v[2], c = bits.Sub64(a[2], b[2], c)
+	v[3], c = bits.Sub64(a[3], b[3], c)
+
+	// Wrapping around added 2^256, which is 38 mod p, so take it away again. If that
+	// wraps too, v is now at least 2^256 - 38, so taking it away a second time can't.
+	v[0], c = bits.Sub64(v[0], c*38, 0)
+	v[1], c = bits.Sub64(v[1], 0, c)
+	v[2], c = bits.Sub64(v[2], 0, c)
+	v[3], c = bits.Sub64(v[3], 0, c)
+	v[0] -= c * 38
+
+	return v
+}
+
+// Mul sets v = a * b, and returns v.
+func (v *Element) Mul(a, b *Element) *Element {
+	feMul(v, a, b)
+	return v
+}
+
+// Square sets v = a * a, and returns v.
+func (v *Element) Square(a *Element) *Element {
+	feSquare(v, a)
+	return v
+}
+
+// SquareN sets v = a^(2^n), and returns v. n must be at least 1.
+func (v *Element) SquareN(a *Element, n int) *Element {
+	feSquareN(v, a, n)
+	return v
+}
+
+// Invert sets v = 1/a, and returns v. If a is zero, v is set to zero.
+func (v *Element) Invert(a *Element) *Element {
+	// a^(p-2) = a^(2^255-21)
+	var z2250, z11 Element
+	pow22501(&z2250, &z11, a)
+	z2250.SquareN(&z2250, 5)
+
+	return v.Mul(&z2250, &z11)
+}
+
+// Pow22523 sets v = a^((p-5)/8), which is used to compute square roots, and returns v.
+func (v *Element) Pow22523(a *Element) *Element {
+	// a^((p-5)/8) = a^(2^252-3)
+	var z2250, z11 Element
+	pow22501(&z2250, &z11, a)
+	z2250.SquareN(&z2250, 2)
+
+	return v.Mul(&z2250, a)
+}
+
+// pow22501 sets z2250 = a^(2^250-1) and z11 = a^11, which are shared by Invert and
+// Pow22523. It uses the same addition chain as ref10.
+func pow22501(z2250, z11, a *Element) {
+	var z2, z9, z250, z2100, z2200, z2500, z21000, z22000, t Element
+
+	z2.Square(a)
+	t.SquareN(&z2, 2)
+	z9.Mul(&t, a)
+	z11.Mul(&z9, &z2)
+	t.Square(z11)
+	z250.Mul(&t, &z9) // 2^5 - 1
+
+	t.SquareN(&z250, 5)
+	z2100.Mul(&t, &z250) // 2^10 - 1
+	t.SquareN(&z2100, 10)
+	z2200.Mul(&t, &z2100) // 2^20 - 1
+	t.SquareN(&z2200, 20)
+	t.Mul(&t, &z2200) // 2^40 - 1
+	t.SquareN(&t, 10)
+	z2500.Mul(&t, &z2100) // 2^50 - 1
+	t.SquareN(&z2500, 50)
+	z21000.Mul(&t, &z2500) // 2^100 - 1
+	t.SquareN(&z21000, 100)
+	z22000.Mul(&t, &z21000) // 2^200 - 1
+	t.SquareN(&z22000, 50)
+	z2250.Mul(&t, &z2500) // 2^250 - 1
+}
+
+// feMulGeneric sets out = a * b.
+func feMulGeneric(out, a, b *Element) {
+	var t [8]uint64
+	for i := 0; i < 4; i++ {
+		var carry uint64
+		for j := 0; j < 4; j++ {
+			hi, lo := bits.Mul64(a[i], b[j])
+
+			var c uint64
+			lo, c = bits.Add64(lo, t[i+j], 0)
+			hi += c
+			lo, c = bits.Add64(lo, carry, 0)
+			hi += c
+
+			t[i+j] = lo
+			carry = hi
+		}
+		t[i+4] = carry
+	}
+
+	reduceWide(out, &t)
+}
+
+// feSquareGeneric sets out = a * a.
+func feSquareGeneric(out, a *Element) {
+	feMulGeneric(out, a, a)
+}
+
+// feSquareNGeneric sets out = a^(2^n).
+func feSquareNGeneric(out, a *Element, n int) {
+	feSquareGeneric(out, a)
+	for i := 1; i < n; i++ {
+		feSquareGeneric(out, out)
+	}
+}
+
+// reduceWide sets out to the 512-bit value t, reduced below 2^256.
+func reduceWide(out *Element, t *[8]uint64) {
+	// 2^256 = 38 mod p, so add the top half times 38 to the bottom half.
+	var carry uint64
+	for i := 0; i < 4; i++ {
+		hi, lo := bits.Mul64(t[i+4], 38)
+
+		var c uint64
+		lo, c = bits.Add64(lo, t[i], 0)
+		hi += c
+		lo, c = bits.Add64(lo, carry, 0)
+		hi += c
+
+		out[i] = lo
+		carry = hi
+	}
+
+	// Fold what's left over above 2^256 back in the same way. If that carries, out is
+	// now small, so adding 38 once more can't.
+	var c uint64
+	out[0], c = bits.Add64(out[0], carry*38, 0)
+	out[1], c = bits.Add64(out[1], 0, c)
+	out[2], c = bits.Add64(out[2], 0, c)
+	out[3], c = bits.Add64(out[3], 0, c)
+	out[0] += c * 38
+}
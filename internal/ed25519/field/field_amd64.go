@@ -0,0 +1,43 @@
+//go:build amd64 && !purego
+// +build amd64,!purego
+
+package field
+
+import "golang.org/x/sys/cpu"
+
+// useMULX is true if the CPU supports the BMI2 and ADX instructions that the assembly
+// needs. Older CPUs use the generic Go code instead.
+var useMULX = cpu.X86.HasBMI2 && cpu.X86.HasADX
+
+func feMul(out, a, b *Element) {
+	if useMULX {
+		feMulMULX(out, a, b)
+	} else {
+		feMulGeneric(out, a, b)
+	}
+}
+
+func feSquare(out, a *Element) {
+	if useMULX {
+		feSquareMULX(out, a)
+	} else {
+		feSquareGeneric(out, a)
+	}
+}
+
+func feSquareN(out, a *Element, n int) {
+	if useMULX {
+		feSquareNMULX(out, a, n)
+	} else {
+		feSquareNGeneric(out, a, n)
+	}
+}
+
+//go:noescape
+func feMulMULX(out, a, b *Element)
+
+//go:noescape
+func feSquareMULX(out, a *Element)
+
+//go:noescape
+func feSquareNMULX(out, a *Element, n int)
@@ -0,0 +1,188 @@
+//go:build amd64 && !purego
+// +build amd64,!purego
+
+#include "textflag.h"
+
+// REDUCE reduces the 512-bit value in R8-R15, least significant limb first, below 2^256
+// and stores it in out. It uses 2^256 = 38 mod p, so the top 4 limbs times 38 are added
+// to the bottom 4, then what's left over above 2^256 is folded in the same way.
+// Clobbers AX, CX and DX, and leaves out in DI.
+#define REDUCE \
+	MOVQ  $38, DX        \
+	XORQ  CX, CX         \
+	MULXQ R12, AX, R12   \
+	ADOXQ AX, R8         \
+	ADCXQ R12, R9        \
+	MULXQ R13, AX, R13   \
+	ADOXQ AX, R9         \
+	ADCXQ R13, R10       \
+	MULXQ R14, AX, R14   \
+	ADOXQ AX, R10        \
+	ADCXQ R14, R11       \
+	MULXQ R15, AX, R15   \
+	ADOXQ AX, R11        \
+	ADCXQ CX, R15        \
+	ADOXQ CX, R15        \
+	IMULQ $38, R15       \
+	ADDQ  R15, R8        \
+	ADCQ  CX, R9         \
+	ADCQ  CX, R10        \
+	ADCQ  CX, R11        \
+	CMOVQCS DX, CX     \
+	ADDQ  CX, R8         \
+	MOVQ  out+0(FP), DI  \
+	MOVQ  R8, 0(DI)      \
+	MOVQ  R9, 8(DI)      \
+	MOVQ  R10, 16(DI)    \
+	MOVQ  R11, 24(DI)
+
+// SQUARE computes the 512-bit square of the element at SI into R8-R15, least
+// significant limb first. The cross products a[i] * a[j], for i < j, are computed
+// once and doubled, then the squares a[i] * a[i] are added. Clobbers AX, BX, CX and DX.
+#define SQUARE \
+	MOVQ  0(SI), DX      \
+	MULXQ 8(SI), R9, R10 \
+	MULXQ 16(SI), AX, R11\
+	ADDQ  AX, R10        \
+	MULXQ 24(SI), AX, R12\
+	ADCQ  AX, R11        \
+	ADCQ  $0, R12        \
+	MOVQ  8(SI), DX      \
+	XORQ  CX, CX         \
+	MULXQ 16(SI), AX, BX \
+	ADOXQ AX, R11        \
+	ADCXQ BX, R12        \
+	MULXQ 24(SI), AX, R13\
+	ADOXQ AX, R12        \
+	ADCXQ CX, R13        \
+	ADOXQ CX, R13        \
+	MOVQ  16(SI), DX     \
+	MULXQ 24(SI), AX, R14\
+	ADDQ  AX, R13        \
+	ADCQ  $0, R14        \
+	XORQ  R15, R15       \
+	ADDQ  R9, R9         \
+	ADCQ  R10, R10       \
+	ADCQ  R11, R11       \
+	ADCQ  R12, R12       \
+	ADCQ  R13, R13       \
+	ADCQ  R14, R14       \
+	ADCQ  $0, R15        \
+	MOVQ  0(SI), DX      \
+	MULXQ DX, R8, AX     \
+	ADDQ  AX, R9         \
+	MOVQ  8(SI), DX      \
+	MULXQ DX, AX, BX     \
+	ADCQ  AX, R10        \
+	ADCQ  BX, R11        \
+	MOVQ  16(SI), DX     \
+	MULXQ DX, AX, BX     \
+	ADCQ  AX, R12        \
+	ADCQ  BX, R13        \
+	MOVQ  24(SI), DX     \
+	MULXQ DX, AX, BX     \
+	ADCQ  AX, R14        \
+	ADCQ  BX, R15
+
+// func feMulMULX(out, a, b *Element)
+TEXT ·feMulMULX(SB), NOSPLIT, $0-24
+	MOVQ a+8(FP), SI
+	MOVQ b+16(FP), DI
+
+	// Row 0: a0 * b.
+	MOVQ  0(SI), DX
+	MULXQ 0(DI), R8, R9
+	MULXQ 8(DI), AX, R10
+	ADDQ  AX, R9
+	MULXQ 16(DI), AX, R11
+	ADCQ  AX, R10
+	MULXQ 24(DI), AX, R12
+	ADCQ  AX, R11
+	ADCQ  $0, R12
+
+	// Row 1: a1 * b, added from R9. The low halves are added with the OF carry chain
+	// and the high halves with the CF carry chain, so they don't wait on each other.
+	MOVQ  8(SI), DX
+	XORQ  CX, CX
+	MULXQ 0(DI), AX, BX
+	ADOXQ AX, R9
+	ADCXQ BX, R10
+	MULXQ 8(DI), AX, BX
+	ADOXQ AX, R10
+	ADCXQ BX, R11
+	MULXQ 16(DI), AX, BX
+	ADOXQ AX, R11
+	ADCXQ BX, R12
+	MULXQ 24(DI), AX, R13
+	ADOXQ AX, R12
+	ADCXQ CX, R13
+	ADOXQ CX, R13
+
+	// Row 2: a2 * b, added from R10.
+	MOVQ  16(SI), DX
+	XORQ  CX, CX
+	MULXQ 0(DI), AX, BX
+	ADOXQ AX, R10
+	ADCXQ BX, R11
+	MULXQ 8(DI), AX, BX
+	ADOXQ AX, R11
+	ADCXQ BX, R12
+	MULXQ 16(DI), AX, BX
+	ADOXQ AX, R12
+	ADCXQ BX, R13
+	MULXQ 24(DI), AX, R14
+	ADOXQ AX, R13
+	ADCXQ CX, R14
+	ADOXQ CX, R14
+
+	// Row 3: a3 * b, added from R11.
+	MOVQ  24(SI), DX
+	XORQ  CX, CX
+	MULXQ 0(DI), AX, BX
+	ADOXQ AX, R11
+	ADCXQ BX, R12
+	MULXQ 8(DI), AX, BX
+	ADOXQ AX, R12
+	ADCXQ BX, R13
+	MULXQ 16(DI), AX, BX
+	ADOXQ AX, R13
+	ADCXQ BX, R14
+	MULXQ 24(DI), AX, R15
+	ADOXQ AX, R14
+	ADCXQ CX, R15
+	ADOXQ CX, R15
+
+	REDUCE
+	RET
+
+// func feSquareMULX(out, a *Element)
+TEXT ·feSquareMULX(SB), NOSPLIT, $0-16
+	MOVQ a+8(FP), SI
+	SQUARE
+	REDUCE
+	RET
+
+// func feSquareNMULX(out, a *Element, n int)
+TEXT ·feSquareNMULX(SB), NOSPLIT, $0-24
+	MOVQ a+8(FP), SI
+	SQUARE
+	REDUCE
+
+	// Square out in place for the remaining n-1 times.
+	MOVQ DI, SI
+	MOVQ n+16(FP), BX
+	DECQ BX
+
+	// BX is kept in n between iterations, as SQUARE and REDUCE use every other free
+	// register.
+loop:
+	JLE  done
+	MOVQ BX, n+16(FP)
+	SQUARE
+	REDUCE
+	MOVQ n+16(FP), BX
+	DECQ BX
+	JMP  loop
+
+done:
+	RET
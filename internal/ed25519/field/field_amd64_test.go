@@ -0,0 +1,40 @@
+//go:build amd64 && !purego
+// +build amd64,!purego
+
+package field
+
+import "testing"
+
+func TestMULXMatchesGeneric(t *testing.T) {
+	t.Parallel()
+
+	if !useMULX {
+		t.Skip("CPU does not support BMI2 and ADX")
+	}
+
+	elems := testElements(t, 1000)
+	for i := range elems {
+		a := &elems[i]
+		b := &elems[(i+7)%len(elems)]
+
+		var got, want Element
+		feMulMULX(&got, a, b)
+		feMulGeneric(&want, a, b)
+		if got != want {
+			t.Fatalf("feMulMULX(%x, %x) = %x, wanted: %x", *a, *b, got, want)
+		}
+
+		feSquareMULX(&got, a)
+		feSquareGeneric(&want, a)
+		if got != want {
+			t.Fatalf("feSquareMULX(%x) = %x, wanted: %x", *a, got, want)
+		}
+
+		n := i%20 + 1
+		feSquareNMULX(&got, a, n)
+		feSquareNGeneric(&want, a, n)
+		if got != want {
+			t.Fatalf("feSquareNMULX(%x, %d) = %x, wanted: %x", *a, n, got, want)
+		}
+	}
+}
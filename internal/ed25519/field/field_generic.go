@@ -0,0 +1,16 @@
+//go:build !amd64 || purego
+// +build !amd64 purego
+
+package field
+
+func feMul(out, a, b *Element) {
+	feMulGeneric(out, a, b)
+}
+
+func feSquare(out, a *Element) {
+	feSquareGeneric(out, a)
+}
+
+func feSquareN(out, a *Element, n int) {
+	feSquareNGeneric(out, a, n)
+}
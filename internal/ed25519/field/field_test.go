@@ -0,0 +1,128 @@
+package field
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+var p, _ = new(big.Int).SetString("7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffed", 16)
+
+// testElements returns random elements, plus the edge cases that the carry handling has
+// to get right: values near 0, p and 2^256.
+func testElements(t testing.TB, n int) []Element {
+	t.Helper()
+
+	max := ^uint64(0)
+	elems := []Element{
+		{},
+		{1, 0, 0, 0},
+		{max, max, max, max},
+		{max - 37, max, max, max},
+		{0xffffffffffffffed, max, max, mask63},
+		{0xffffffffffffffec, max, max, mask63},
+		{0xffffffffffffffee, max, max, mask63},
+		{0, 0, 0, 1 << 63},
+	}
+
+	var buf [32]byte
+	for i := 0; i < n; i++ {
+		if _, err := rand.Read(buf[:]); err != nil {
+			t.Fatalf("could not read random bytes: %v", err)
+		}
+
+		var v Element
+		v.SetBytes(&buf)
+		v[3] |= uint64(buf[31]>>7) << 63
+		elems = append(elems, v)
+	}
+
+	return elems
+}
+
+func toBig(v *Element) *big.Int {
+	b := new(big.Int)
+	for i := 3; i >= 0; i-- {
+		b.Lsh(b, 64)
+		b.Or(b, new(big.Int).SetUint64(v[i]))
+	}
+
+	return b
+}
+
+func assertEqualMod(t *testing.T, op string, got *Element, want *big.Int) {
+	t.Helper()
+
+	want = new(big.Int).Mod(want, p)
+
+	var b [32]byte
+	got.Bytes(&b)
+	gotBig := new(big.Int)
+	for i := 31; i >= 0; i-- {
+		gotBig.Lsh(gotBig, 8)
+		gotBig.Or(gotBig, big.NewInt(int64(b[i])))
+	}
+
+	if gotBig.Cmp(want) != 0 {
+		t.Fatalf("%s: got %x, wanted %x", op, gotBig, want)
+	}
+}
+
+func TestElement(t *testing.T) {
+	t.Parallel()
+
+	elems := testElements(t, 200)
+	for i := range elems {
+		a := &elems[i]
+		b := &elems[(i+1)%len(elems)]
+		ab, bb := toBig(a), toBig(b)
+
+		var v Element
+		assertEqualMod(t, "Add", v.Add(a, b), new(big.Int).Add(ab, bb))
+		assertEqualMod(t, "Sub", v.Sub(a, b), new(big.Int).Sub(ab, bb))
+		assertEqualMod(t, "Mul", v.Mul(a, b), new(big.Int).Mul(ab, bb))
+		assertEqualMod(t, "Square", v.Square(a), new(big.Int).Mul(ab, ab))
+		assertEqualMod(t, "SquareN", v.SquareN(a, 3), new(big.Int).Exp(ab, big.NewInt(8), p))
+
+		feMulGeneric(&v, a, b)
+		assertEqualMod(t, "feMulGeneric", &v, new(big.Int).Mul(ab, bb))
+		feSquareGeneric(&v, a)
+		assertEqualMod(t, "feSquareGeneric", &v, new(big.Int).Mul(ab, ab))
+
+		assertEqualMod(t, "Invert", v.Invert(a), new(big.Int).Exp(ab, new(big.Int).Sub(p, big.NewInt(2)), p))
+
+		e := new(big.Int).Sub(p, big.NewInt(5))
+		e.Rsh(e, 3)
+		assertEqualMod(t, "Pow22523", v.Pow22523(a), new(big.Int).Exp(ab, e, p))
+
+		var c Element
+		c.Add(a, &Element{}) // Same value, possibly different representation.
+		if a.Equal(&c) != 1 || (a.Equal(b) == 1) != (new(big.Int).Mod(ab, p).Cmp(new(big.Int).Mod(bb, p)) == 0) {
+			t.Fatalf("Equal gave the wrong result for %x and %x", ab, bb)
+		}
+		if a.IsNegative() != uint64(new(big.Int).Mod(ab, p).Bit(0)) {
+			t.Fatalf("IsNegative gave the wrong result for %x", ab)
+		}
+	}
+}
+
+func BenchmarkElement_Mul(b *testing.B) {
+	v := testElements(b, 2)
+	for i := 0; i < b.N; i++ {
+		v[0].Mul(&v[0], &v[1])
+	}
+}
+
+func BenchmarkElement_Square(b *testing.B) {
+	v := testElements(b, 1)
+	for i := 0; i < b.N; i++ {
+		v[0].Square(&v[0])
+	}
+}
+
+func BenchmarkElement_Invert(b *testing.B) {
+	v := testElements(b, 1)
+	for i := 0; i < b.N; i++ {
+		v[0].Invert(&v[0])
+	}
+}
@@ -1,21 +1,38 @@
 package ed25519
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"math"
 
-	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/innix/shrek/internal/ed25519/field"
 	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
 )
 
-type keyIterator struct {
-	kp      *KeyPair
-	eightPt *curve.EdwardsPoint
-
-	pt *curve.EdwardsPoint
-	sc *scalar.Scalar
+// keyBatchSize is how many public keys the iterator computes at a time. Compressing a
+// point needs a field inversion, which costs far more than the point addition, so it's
+// shared between a batch of points; see compressBatch.
+const keyBatchSize = 64
+
+// KeyIterator generates a sequence of Ed25519 key pairs, where each key is derived from
+// the previous one with a single point addition instead of a full scalar multiplication.
+type KeyIterator struct {
+	// prefix is the second half of the starting private key, which is the same for
+	// every key the iterator generates.
+	prefix [PrivateKeySize - scalar.ScalarSize]byte
+	sc     [scalar.ScalarSize]byte
+
+	// pt is the point that the next batch starts from.
+	pt point
+
+	// batch holds the public keys of the current batch, and idx is the position of the
+	// current key in it. pts and zs are scratch space used to compute the batch.
+	batch [keyBatchSize][PublicKeySize]byte
+	idx   int
+	pts   [keyBatchSize]point
+	zs    [keyBatchSize]field.Element
 
 	counter uint64
 }
@@ -23,90 +40,189 @@ type keyIterator struct {
 // NewKeyIterator creates and initializes a new Ed25519 key iterator.
 // The iterator is NOT thread safe; you must create a separate iterator for
 // each worker instead of sharing a single instance.
-func NewKeyIterator(rand io.Reader) (*keyIterator, error) {
-	eightPt := curve.NewEdwardsPoint()
-	eightPt = eightPt.MulBasepoint(curve.ED25519_BASEPOINT_TABLE, scalar.NewFromUint64(8))
+func NewKeyIterator(rand io.Reader) (*KeyIterator, error) {
+	it := &KeyIterator{}
+	if err := it.init(rand); err != nil {
+		return nil, err
+	}
+	it.fillBatch()
+
+	return it, nil
+}
+
+// NewKeyIteratorAt creates a new Ed25519 key iterator that starts from the key derived
+// from seed, advanced by counter. It can be used to resume an iterator from a known
+// state. The counter must be a multiple of 8.
+func NewKeyIteratorAt(seed []byte, counter uint64) (*KeyIterator, error) {
+	if l := len(seed); l != SeedSize {
+		return nil, fmt.Errorf("ed25519: bad seed length: %d", l)
+	}
+	if counter%8 != 0 {
+		return nil, fmt.Errorf("ed25519: counter must be a multiple of 8: %d", counter)
+	}
 
-	it := &keyIterator{
-		eightPt: eightPt,
+	it := &KeyIterator{}
+	if err := it.init(bytes.NewReader(seed)); err != nil {
+		return nil, err
 	}
-	if _, err := it.init(rand); err != nil {
+	if err := it.advance(counter); err != nil {
 		return nil, err
 	}
+	it.fillBatch()
 
 	return it, nil
 }
 
-func (it *keyIterator) Next() bool {
+// NewKeyIteratorFromKey creates a new Ed25519 key iterator that starts from the 64 byte
+// expanded private key sk, advanced by counter. Along with StartKey and Counter, it can
+// be used to save the state of an iterator and resume it later. The counter must be a
+// multiple of 8.
+func NewKeyIteratorFromKey(sk PrivateKey, counter uint64) (*KeyIterator, error) {
+	if counter%8 != 0 {
+		return nil, fmt.Errorf("ed25519: counter must be a multiple of 8: %d", counter)
+	}
+
+	kp, err := NewKeyPair(sk)
+	if err != nil {
+		return nil, err
+	}
+
+	it := &KeyIterator{}
+	if err := it.setKey(kp); err != nil {
+		return nil, err
+	}
+	if err := it.advance(counter); err != nil {
+		return nil, err
+	}
+	it.fillBatch()
+
+	return it, nil
+}
+
+func (it *KeyIterator) Next() bool {
 	const maxCounter = math.MaxUint64 - 8
 
 	if it.counter > uint64(maxCounter) {
 		return false
 	}
 
-	it.pt = it.pt.Add(it.pt, it.eightPt)
 	it.counter += 8
+	it.idx++
+	if it.idx == keyBatchSize {
+		it.fillBatch()
+	}
 
 	return true
 }
 
-func (it *keyIterator) PublicKey() PublicKey {
-	var pk curve.CompressedEdwardsY
-	pk.SetEdwardsPoint(it.pt)
+// Counter returns how far the iterator has advanced from its starting key. It's
+// incremented by 8 on each call to Next.
+func (it *KeyIterator) Counter() uint64 {
+	return it.counter
+}
+
+func (it *KeyIterator) PublicKey() PublicKey {
+	pk := make(PublicKey, PublicKeySize)
+	copy(pk, it.batch[it.idx][:])
 
-	return pk[:]
+	return pk
 }
 
-func (it *keyIterator) PrivateKey() (PrivateKey, error) {
-	sc := scalar.New().Set(it.sc)
+// StartKey returns the private key that the iterator started from, before Next was
+// first called. It's as secret as the keys the iterator generates, because they can all
+// be derived from it.
+func (it *KeyIterator) StartKey() PrivateKey {
+	sk := make([]byte, PrivateKeySize)
+	copy(sk, it.sc[:])
+	copy(sk[scalar.ScalarSize:], it.prefix[:])
 
-	if it.counter > 0 {
-		scalarAdd(sc, it.counter)
-	}
+	return sk
+}
+
+func (it *KeyIterator) PrivateKey() (PrivateKey, error) {
+	var sc [scalar.ScalarSize]byte
+	defer Wipe(sc[:])
+	scalarAdd(&sc, &it.sc, it.counter)
 
 	sk := make([]byte, PrivateKeySize)
-	if err := sc.ToBytes(sk[:scalar.ScalarSize]); err != nil {
-		panic(err)
-	}
-	copy(sk[scalar.ScalarSize:], it.kp.PrivateKey[scalar.ScalarSize:])
+	copy(sk, sc[:])
+	copy(sk[scalar.ScalarSize:], it.prefix[:])
 
 	// Sanity check.
-	if !((sk[0] & 248) == sk[0]) || !(((sk[31] & 63) | 64) == sk[31]) {
+	if !isClamped(sk) {
 		return nil, errors.New("sanity check on private key failed")
 	}
 
 	return sk, nil
 }
 
-func (it *keyIterator) init(rand io.Reader) (*KeyPair, error) {
+// Destroy wipes the secret key material held by the iterator. The iterator must not be
+// used afterwards.
+func (it *KeyIterator) Destroy() {
+	Wipe(it.prefix[:])
+	Wipe(it.sc[:])
+}
+
+func (it *KeyIterator) init(rand io.Reader) error {
 	kp, err := GenerateKey(rand)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	// Parse private key.
-	sk, err := scalar.NewFromBits(kp.PrivateKey[:scalar.ScalarSize])
-	if err != nil {
-		return nil, fmt.Errorf("ed25519: could not parse scalar from private key: %w", err)
-	}
+	// The private key itself isn't kept, only the parts of it that are needed later.
+	defer Wipe(kp.PrivateKey)
 
+	return it.setKey(kp)
+}
+
+func (it *KeyIterator) setKey(kp *KeyPair) error {
 	// Parse public key.
-	cpt, err := curve.NewCompressedEdwardsYFromBytes(kp.PublicKey)
-	if err != nil {
-		return nil, fmt.Errorf("ed25519: could not parse point from public key: %w", err)
-	}
-	pk := curve.NewEdwardsPoint()
-	if _, err := pk.SetCompressedY(cpt); err != nil {
-		return nil, fmt.Errorf("ed25519: could not decompress point from public key: %w", err)
+	var cpt [PublicKeySize]byte
+	copy(cpt[:], kp.PublicKey)
+	if err := it.pt.setCompressed(&cpt); err != nil {
+		return fmt.Errorf("ed25519: could not decompress point from public key: %w", err)
 	}
 
 	// Cache data so it can be used later.
-	it.kp = kp
-	it.sc = sk
-	it.pt = pk
+	copy(it.sc[:], kp.PrivateKey[:scalar.ScalarSize])
+	copy(it.prefix[:], kp.PrivateKey[scalar.ScalarSize:])
 
 	// Reset counter.
 	it.counter = 0
 
-	return kp, nil
+	return nil
+}
+
+// advance moves the iterator on from its starting key by counter, which must be a
+// multiple of 8, with a single scalar multiplication. It must be called before the
+// first batch is filled.
+func (it *KeyIterator) advance(counter uint64) error {
+	if counter == 0 {
+		return nil
+	}
+
+	offset, err := basepointMultiple(counter)
+	if err != nil {
+		return fmt.Errorf("ed25519: could not compute offset point: %w", err)
+	}
+
+	var n affineNielsPoint
+	n.setAffineNiels(offset)
+	it.pt.addAffineNiels(&it.pt, &n)
+	it.counter = counter
+
+	return nil
+}
+
+// fillBatch computes the public keys of the next batch, starting from it.pt, and moves
+// it.pt on to the point after the end of the batch.
+func (it *KeyIterator) fillBatch() {
+	it.pts[0] = it.pt
+	for i := 1; i < keyBatchSize; i++ {
+		it.pts[i].addAffineNiels(&it.pts[i-1], eightBasepoint)
+	}
+	it.pt.addAffineNiels(&it.pts[keyBatchSize-1], eightBasepoint)
+
+	compressBatch(it.batch[:], it.pts[:], it.zs[:])
+	it.idx = 0
 }
@@ -1,6 +1,7 @@
 package ed25519_test
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/innix/shrek/internal/ed25519"
@@ -20,3 +21,86 @@ func BenchmarkKeyIterator_PublicKeyAndNext(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkKeyIterator_PrivateKey(b *testing.B) {
+	it, err := ed25519.NewKeyIterator(nil)
+	if err != nil {
+		b.Fatalf("could not create key iterator: %v", err)
+	}
+	it.Next()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := it.PrivateKey(); err != nil {
+			b.Fatalf("could not get private key: %v", err)
+		}
+	}
+}
+
+func TestKeyIterator(t *testing.T) {
+	t.Parallel()
+
+	seed := bytes.Repeat([]byte{7}, ed25519.SeedSize)
+	it, err := ed25519.NewKeyIteratorAt(seed, 0)
+	if err != nil {
+		t.Fatalf("could not create key iterator: %v", err)
+	}
+
+	// Go past a few batches, checking each public key against the one computed from
+	// scratch for its private key.
+	for i := 0; i < 200; i++ {
+		sk, err := it.PrivateKey()
+		if err != nil {
+			t.Fatalf("could not get private key: %v", err)
+		}
+		kp, err := ed25519.NewKeyPair(sk)
+		if err != nil {
+			t.Fatalf("could not create key pair: %v", err)
+		}
+		if pk := it.PublicKey(); !bytes.Equal(pk, kp.PublicKey) {
+			t.Fatalf("public key at counter %d does not match private key, got: %x, wanted: %x", it.Counter(), pk, kp.PublicKey)
+		}
+
+		it.Next()
+	}
+
+	// Starting part way through should give the same keys as stepping there.
+	at, err := ed25519.NewKeyIteratorAt(seed, it.Counter())
+	if err != nil {
+		t.Fatalf("could not create key iterator: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		if !bytes.Equal(at.PublicKey(), it.PublicKey()) {
+			t.Fatalf("iterators diverged at counter %d", it.Counter())
+		}
+		it.Next()
+		at.Next()
+	}
+}
+
+func TestKeyIterator_Destroy(t *testing.T) {
+	t.Parallel()
+
+	it, err := ed25519.NewKeyIterator(nil)
+	if err != nil {
+		t.Fatalf("could not create key iterator: %v", err)
+	}
+	it.Next()
+
+	sk, err := it.PrivateKey()
+	if err != nil {
+		t.Fatalf("could not get private key: %v", err)
+	}
+	it.Destroy()
+
+	// The keys already handed out are owned by the caller, so must be left alone.
+	kp := &ed25519.KeyPair{PublicKey: it.PublicKey(), PrivateKey: sk}
+	if err := kp.Validate(); err != nil {
+		t.Errorf("private key handed out before destroy was changed: %v", err)
+	}
+
+	// The iterator's own secrets are gone, so it can no longer produce the private key.
+	if after, err := it.PrivateKey(); err == nil && bytes.Equal(after, sk) {
+		t.Errorf("iterator still produces the private key after destroy")
+	}
+}
@@ -0,0 +1,172 @@
+package ed25519
+
+import (
+	"errors"
+
+	"github.com/innix/shrek/internal/ed25519/field"
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+)
+
+var (
+	// curveD is the d parameter of the curve, -121665/121666.
+	curveD = field.Element{0x75eb4dca135978a3, 0x00700a4d4141d8ab, 0x8cc740797779e898, 0x52036cee2b6ffe73}
+
+	// sqrtM1 is a square root of -1.
+	sqrtM1 = field.Element{0xc4ee1b274a0ea0b0, 0x2f431806ad2fe478, 0x2b4d00993dfbd7a7, 0x2b8324804fc1df0b}
+
+	// eightBasepoint is 8 times the basepoint, which is added to the iterator's point on
+	// each step.
+	eightBasepoint = mustBasepointMultiple(8)
+)
+
+// point is a point on the curve in extended coordinates (X:Y:Z:T), where x = X/Z,
+// y = Y/Z and xy = T/Z. The iterator uses these instead of curve.EdwardsPoint so that
+// it can share one field inversion between a whole batch of points.
+type point struct {
+	X, Y, Z, T field.Element
+}
+
+// affineNielsPoint is a point with Z = 1, stored in the form that's cheapest to add to a
+// point: (y+x, y-x, 2dxy).
+type affineNielsPoint struct {
+	YPlusX, YMinusX, XY2D field.Element
+}
+
+// setCompressed decodes a 32 byte compressed point into p, as described in section
+// 5.1.3 of RFC 8032.
+func (p *point) setCompressed(b *[32]byte) error {
+	var y, y2, u, v, v3, x, vxx, t field.Element
+	var one field.Element
+	one.One()
+
+	y.SetBytes(b)
+	y2.Square(&y)
+	u.Sub(&y2, &one)
+	v.Mul(&y2, &curveD)
+	v.Add(&v, &one)
+
+	// x = u * v^3 * (u * v^7)^((p-5)/8)
+	v3.Square(&v)
+	v3.Mul(&v3, &v)
+	t.Square(&v3)
+	t.Mul(&t, &v)
+	t.Mul(&t, &u)
+	t.Pow22523(&t)
+	x.Mul(&u, &v3)
+	x.Mul(&x, &t)
+
+	vxx.Square(&x)
+	vxx.Mul(&vxx, &v)
+	if vxx.Equal(&u) != 1 {
+		t.Sub(&field.Element{}, &u)
+		if vxx.Equal(&t) != 1 {
+			return errors.New("ed25519: point is not on the curve")
+		}
+		x.Mul(&x, &sqrtM1)
+	}
+
+	sign := uint64(b[31] >> 7)
+	if x.IsNegative() != sign {
+		if x.Equal(&field.Element{}) == 1 {
+			return errors.New("ed25519: point is not valid")
+		}
+		x.Sub(&field.Element{}, &x)
+	}
+
+	p.X = x
+	p.Y = y
+	p.Z.One()
+	p.T.Mul(&x, &y)
+
+	return nil
+}
+
+// setAffineNiels sets n to p, which must have Z = 1.
+func (n *affineNielsPoint) setAffineNiels(p *point) {
+	n.YPlusX.Add(&p.Y, &p.X)
+	n.YMinusX.Sub(&p.Y, &p.X)
+	n.XY2D.Mul(&p.T, &curveD)
+	n.XY2D.Add(&n.XY2D, &n.XY2D)
+}
+
+// addAffineNiels sets p = q + n, using the "madd-2008-hwcd-3" formulas, and returns p.
+func (p *point) addAffineNiels(q *point, n *affineNielsPoint) *point {
+	var a, b, c, d, e, f, g, h field.Element
+
+	a.Sub(&q.Y, &q.X)
+	a.Mul(&a, &n.YMinusX)
+	b.Add(&q.Y, &q.X)
+	b.Mul(&b, &n.YPlusX)
+	c.Mul(&q.T, &n.XY2D)
+	d.Add(&q.Z, &q.Z)
+
+	e.Sub(&b, &a)
+	f.Sub(&d, &c)
+	g.Add(&d, &c)
+	h.Add(&b, &a)
+
+	p.X.Mul(&e, &f)
+	p.Y.Mul(&g, &h)
+	p.T.Mul(&e, &h)
+	p.Z.Mul(&f, &g)
+
+	return p
+}
+
+// compressBatch writes the 32 byte compressed form of each point in pts to out. It uses
+// Montgomery's trick to share a single field inversion between all of the points, so it
+// costs 3 multiplications per point instead of an inversion. zs must be at least as long
+// as pts, and is used as scratch space.
+func compressBatch(out [][PublicKeySize]byte, pts []point, zs []field.Element) {
+	// zs[i] = Z[0] * Z[1] * ... * Z[i]
+	zs[0] = pts[0].Z
+	for i := 1; i < len(pts); i++ {
+		zs[i].Mul(&zs[i-1], &pts[i].Z)
+	}
+
+	// inv = 1 / (Z[0] * ... * Z[i]), working backwards to peel off 1 / Z[i] each time.
+	var inv, zinv, x, y field.Element
+	inv.Invert(&zs[len(pts)-1])
+	for i := len(pts) - 1; i >= 0; i-- {
+		if i > 0 {
+			zinv.Mul(&inv, &zs[i-1])
+			inv.Mul(&inv, &pts[i].Z)
+		} else {
+			zinv = inv
+		}
+
+		x.Mul(&pts[i].X, &zinv)
+		y.Mul(&pts[i].Y, &zinv)
+		y.Bytes(&out[i])
+		out[i][31] |= byte(x.IsNegative() << 7)
+	}
+}
+
+// basepointMultiple returns n times the basepoint.
+func basepointMultiple(n uint64) (*point, error) {
+	var cpt curve.CompressedEdwardsY
+	cpt.SetEdwardsPoint(curve.NewEdwardsPoint().MulBasepoint(curve.ED25519_BASEPOINT_TABLE, scalar.NewFromUint64(n)))
+
+	var b [32]byte
+	copy(b[:], cpt[:])
+
+	var p point
+	if err := p.setCompressed(&b); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func mustBasepointMultiple(n uint64) *affineNielsPoint {
+	p, err := basepointMultiple(n)
+	if err != nil {
+		panic(err)
+	}
+
+	var np affineNielsPoint
+	np.setAffineNiels(p)
+
+	return &np
+}
@@ -0,0 +1,84 @@
+package ed25519
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/innix/shrek/internal/ed25519/field"
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+)
+
+// TestPoint checks the point arithmetic used by the iterator against curve25519-voi.
+func TestPoint(t *testing.T) {
+	t.Parallel()
+
+	const steps = 10
+
+	eight := curve.NewEdwardsPoint().MulBasepoint(curve.ED25519_BASEPOINT_TABLE, scalar.NewFromUint64(8))
+
+	var buf [scalar.ScalarSize]byte
+	for i := 0; i < 50; i++ {
+		if _, err := rand.Read(buf[:]); err != nil {
+			t.Fatalf("could not read random bytes: %v", err)
+		}
+		sc, err := scalar.NewFromBits(buf[:])
+		if err != nil {
+			t.Fatalf("could not create scalar: %v", err)
+		}
+
+		// The points P, P + 8B, ..., P + 8(steps-1)B according to curve25519-voi.
+		want := make([][PublicKeySize]byte, steps)
+		vp := curve.NewEdwardsPoint().MulBasepoint(curve.ED25519_BASEPOINT_TABLE, sc)
+		for j := range want {
+			var cpt curve.CompressedEdwardsY
+			cpt.SetEdwardsPoint(vp)
+			copy(want[j][:], cpt[:])
+			vp.Add(vp, eight)
+		}
+
+		// The same points computed by decompressing P, then adding 8B to it.
+		var pts [steps]point
+		if err := pts[0].setCompressed(&want[0]); err != nil {
+			t.Fatalf("could not decompress point %x: %v", want[0], err)
+		}
+		for j := 1; j < steps; j++ {
+			pts[j].addAffineNiels(&pts[j-1], eightBasepoint)
+		}
+
+		got := make([][PublicKeySize]byte, steps)
+		compressBatch(got, pts[:], make([]field.Element, steps))
+
+		for j := range want {
+			if got[j] != want[j] {
+				t.Fatalf("point %d of batch from %x: got %x, wanted %x", j, want[0], got[j], want[j])
+			}
+		}
+	}
+}
+
+// TestPoint_SetCompressed checks that setCompressed accepts and rejects the same
+// encodings as curve25519-voi.
+func TestPoint_SetCompressed(t *testing.T) {
+	t.Parallel()
+
+	var b [32]byte
+	for i := 0; i < 200; i++ {
+		if _, err := rand.Read(b[:]); err != nil {
+			t.Fatalf("could not read random bytes: %v", err)
+		}
+
+		var p point
+		gotErr := p.setCompressed(&b)
+
+		cpt, err := curve.NewCompressedEdwardsYFromBytes(b[:])
+		if err != nil {
+			t.Fatalf("could not parse compressed point: %v", err)
+		}
+		_, wantErr := curve.NewEdwardsPoint().SetCompressedY(cpt)
+
+		if (gotErr == nil) != (wantErr == nil) {
+			t.Fatalf("setCompressed(%x) returned %v, but curve25519-voi returned %v", b, gotErr, wantErr)
+		}
+	}
+}
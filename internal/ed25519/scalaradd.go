@@ -1,34 +1,23 @@
 package ed25519
 
 import (
-	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"encoding/binary"
+	"math/bits"
 )
 
-func scalarAdd(dst *scalar.Scalar, v uint64) {
-	var dstb [32]byte
+// scalarAdd sets dst to the 256-bit little-endian integer s plus v.
+//
+// Unlike scalar.Add, the sum isn't reduced mod l. The private keys generated by the
+// iterator must stay clamped, which a reduced scalar generally isn't, and working on the
+// bytes directly avoids converting to and from a scalar.Scalar on every call.
+func scalarAdd(dst, s *[32]byte, v uint64) {
+	var carry uint64
 
-	// Can't access scalar bytes publicly, so use ToBytes and SetBits.
-	// Kinda slows things down, but have no other choice.
+	for i := 0; i < len(s); i += 8 {
+		limb := binary.LittleEndian.Uint64(s[i:])
+		limb, carry = bits.Add64(limb, v, carry)
+		binary.LittleEndian.PutUint64(dst[i:], limb)
 
-	if err := dst.ToBytes(dstb[:]); err != nil {
-		panic(err)
-	}
-
-	scalarAddBytes(&dstb, v)
-
-	if _, err := dst.SetBits(dstb[:]); err != nil {
-		panic(err)
-	}
-}
-
-func scalarAddBytes(dst *[32]byte, v uint64) {
-	var carry uint32
-
-	for i := 0; i < 32; i++ {
-		carry += uint32(dst[i]) + uint32(v&0xFF)
-		dst[i] = byte(carry & 0xFF)
-		carry >>= 8
-
-		v >>= 8
+		v = 0
 	}
 }
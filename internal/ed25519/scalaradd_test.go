@@ -0,0 +1,109 @@
+package ed25519
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+)
+
+// scalarAddOld is the implementation of scalarAdd that round-tripped through ToBytes and
+// SetBits. It's kept to check that the faster implementation gives the same results.
+func scalarAddOld(dst *scalar.Scalar, v uint64) {
+	var dstb [32]byte
+
+	if err := dst.ToBytes(dstb[:]); err != nil {
+		panic(err)
+	}
+
+	var carry uint32
+	for i := 0; i < 32; i++ {
+		carry += uint32(dstb[i]) + uint32(v&0xFF)
+		dstb[i] = byte(carry & 0xFF)
+		carry >>= 8
+
+		v >>= 8
+	}
+
+	if _, err := dst.SetBits(dstb[:]); err != nil {
+		panic(err)
+	}
+}
+
+func TestScalarAdd(t *testing.T) {
+	t.Parallel()
+
+	var buf [32 + 8]byte
+	for i := 0; i < 1000; i++ {
+		if _, err := rand.Read(buf[:]); err != nil {
+			t.Fatalf("could not read random bytes: %v", err)
+		}
+
+		var start [64]byte
+		copy(start[:], buf[:32])
+		clampSecretKey(&start)
+
+		// Mix in the edge cases the carry handling has to get right.
+		counter := binary.LittleEndian.Uint64(buf[32:]) &^ 7
+		switch i {
+		case 0:
+			counter = 0
+		case 1:
+			counter = math.MaxUint64 &^ 7
+		case 2:
+			// Carry all the way up to the top byte, which is left as small as clamping
+			// allows so the sum stays below 2^255 like every real key does.
+			for j := 8; j < 31; j++ {
+				start[j] = 0xFF
+			}
+			start[31] = 64
+			counter = math.MaxUint64 &^ 7
+		}
+
+		var s [32]byte
+		copy(s[:], start[:32])
+
+		var got [32]byte
+		scalarAdd(&got, &s, counter)
+
+		sc, err := scalar.NewFromBits(s[:])
+		if err != nil {
+			t.Fatalf("could not parse scalar: %v", err)
+		}
+		scalarAddOld(sc, counter)
+
+		var want [32]byte
+		if err := sc.ToBytes(want[:]); err != nil {
+			t.Fatalf("could not encode scalar: %v", err)
+		}
+
+		if !bytes.Equal(got[:], want[:]) {
+			t.Fatalf("scalarAdd(%x, %d) = %x, wanted: %x", s, counter, got, want)
+		}
+	}
+}
+
+func BenchmarkScalarAdd(b *testing.B) {
+	var s, dst [32]byte
+	s[31] = 64
+
+	for i := 0; i < b.N; i++ {
+		scalarAdd(&dst, &s, uint64(i)*8)
+	}
+}
+
+func BenchmarkScalarAddOld(b *testing.B) {
+	var s [32]byte
+	s[31] = 64
+	sc, err := scalar.NewFromBits(s[:])
+	if err != nil {
+		b.Fatalf("could not parse scalar: %v", err)
+	}
+
+	for i := 0; i < b.N; i++ {
+		scalarAddOld(sc, 8)
+	}
+}
@@ -0,0 +1,84 @@
+package ed25519
+
+import (
+	"crypto/sha512"
+	"fmt"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	voied25519 "github.com/oasisprotocol/curve25519-voi/primitives/ed25519"
+)
+
+// SignatureSize is the size, in bytes, of signatures made by Sign.
+const SignatureSize = 64
+
+// Sign signs the message with the 64 byte expanded private key. It produces the same
+// signature as standard Ed25519 implementations do for the seed that the expanded key
+// was derived from, but it doesn't need the seed, so it also works for keys that were
+// never derived from one, such as the keys made by KeyIterator.
+func Sign(sk PrivateKey, pk PublicKey, msg []byte) ([]byte, error) {
+	if l := len(sk); l != PrivateKeySize {
+		return nil, fmt.Errorf("ed25519: bad private key length: %d", l)
+	}
+	if l := len(pk); l != PublicKeySize {
+		return nil, fmt.Errorf("ed25519: bad public key length: %d", l)
+	}
+
+	a, err := scalar.NewFromBytesModOrder(sk[:scalar.ScalarSize])
+	if err != nil {
+		return nil, fmt.Errorf("ed25519: could not parse scalar from private key: %w", err)
+	}
+	defer a.Zero()
+
+	// r = SHA512(prefix || msg), where the prefix is the second half of the expanded key.
+	var digest [sha512.Size]byte
+	defer Wipe(digest[:])
+	h := sha512.New()
+	h.Write(sk[scalar.ScalarSize:])
+	h.Write(msg)
+	r, err := scalar.NewFromBytesModOrderWide(h.Sum(digest[:0]))
+	if err != nil {
+		return nil, fmt.Errorf("ed25519: could not compute nonce: %w", err)
+	}
+	defer r.Zero()
+
+	// R = rB
+	var R curve.CompressedEdwardsY
+	R.SetEdwardsPoint(curve.NewEdwardsPoint().MulBasepoint(curve.ED25519_BASEPOINT_TABLE, r))
+
+	// k = SHA512(R || A || msg)
+	h.Reset()
+	h.Write(R[:])
+	h.Write(pk)
+	h.Write(msg)
+	k, err := scalar.NewFromBytesModOrderWide(h.Sum(nil))
+	if err != nil {
+		return nil, fmt.Errorf("ed25519: could not compute challenge: %w", err)
+	}
+
+	// S = r + k*a mod l
+	S := scalar.New().Mul(k, a)
+	S.Add(S, r)
+
+	sig := make([]byte, SignatureSize)
+	copy(sig, R[:])
+	if err := S.ToBytes(sig[32:]); err != nil {
+		return nil, fmt.Errorf("ed25519: could not encode signature: %w", err)
+	}
+
+	return sig, nil
+}
+
+// verifyOptions makes Verify accept the same signatures as crypto/ed25519 does.
+var verifyOptions = &voied25519.Options{Verify: voied25519.VerifyOptionsStdLib}
+
+// Verify reports whether sig is a valid signature of the message by the public key. It
+// accepts the same signatures as crypto/ed25519, and returns false if the public key has
+// the wrong length.
+func Verify(pk PublicKey, msg, sig []byte) bool {
+	if len(pk) != PublicKeySize {
+		return false
+	}
+
+	return voied25519.VerifyWithOptions(voied25519.PublicKey(pk), msg, sig, verifyOptions)
+}
@@ -0,0 +1,206 @@
+package shrek
+
+import (
+	"bytes"
+	stded25519 "crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/innix/shrek/internal/ed25519"
+)
+
+const controlPortKeyPrefix = "ED25519-V3:"
+
+// ErrNoSecretKey is returned when an operation needs the secret key of an OnionAddress
+// that only has a public key, e.g. one returned by ParseHostName.
+var ErrNoSecretKey = errors.New("shrek: onion address has no secret key")
+
+// NewOnionAddressFromSeed creates an onion address from a standard 32 byte Ed25519
+// seed, such as the one stored in a PKCS#8 file.
+//
+// Note that the conversion only goes one way. Onion addresses store the secret key in
+// Tor's 64 byte expanded form, which is derived by hashing the seed. The seed can't
+// be recovered from the expanded form, so addresses found by MineOnionHostName (which
+// never had a seed) can't be converted into a standard Ed25519 private key.
+func NewOnionAddressFromSeed(seed []byte) (*OnionAddress, error) {
+	kp, err := ed25519.NewKeyFromSeed(seed)
+	if err != nil {
+		return nil, fmt.Errorf("shrek: could not derive onion address from seed: %w", err)
+	}
+
+	return &OnionAddress{
+		PublicKey: kp.PublicKey,
+		SecretKey: kp.PrivateKey,
+	}, nil
+}
+
+// PublicKeyFile returns the contents of Tor's hs_ed25519_public_key file for the
+// address.
+func (addr *OnionAddress) PublicKeyFile() []byte {
+	return append([]byte(publicKeyFileHeader), addr.PublicKey...)
+}
+
+// SecretKeyFile returns the contents of Tor's hs_ed25519_secret_key file for the
+// address. It returns ErrNoSecretKey if the address has no secret key.
+func (addr *OnionAddress) SecretKeyFile() ([]byte, error) {
+	if len(addr.SecretKey) == 0 {
+		return nil, ErrNoSecretKey
+	}
+
+	return append([]byte(secretKeyFileHeader), addr.SecretKey...), nil
+}
+
+// ParseSecretKeyFile parses the contents of Tor's hs_ed25519_secret_key file. The
+// public key is computed from the secret key.
+func ParseSecretKeyFile(data []byte) (*OnionAddress, error) {
+	sk, err := parseKeyFile(data, secretKeyFileHeader, ed25519.PrivateKeySize, "secret")
+	if err != nil {
+		return nil, err
+	}
+
+	return newOnionAddressFromSecretKey(sk)
+}
+
+// ParseControlPortKey parses a secret key in the format used by the ADD_ONION command
+// of Tor's control port, as returned by ControlPortKey.
+func ParseControlPortKey(key string) (*OnionAddress, error) {
+	if !strings.HasPrefix(key, controlPortKeyPrefix) {
+		return nil, fmt.Errorf("shrek: control port key must start with %q", controlPortKeyPrefix)
+	}
+
+	sk, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(key, controlPortKeyPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("shrek: control port key is not valid base64: %w", err)
+	}
+
+	return newOnionAddressFromSecretKey(sk)
+}
+
+// PublicKeyPEM returns the public key as a PEM encoded PKIX "PUBLIC KEY" block, the
+// format used by OpenSSL and most other tooling.
+//
+// There is no equivalent for the secret key, see NewOnionAddressFromSeed for why.
+func (addr *OnionAddress) PublicKeyPEM() ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(stded25519.PublicKey(addr.PublicKey))
+	if err != nil {
+		return nil, fmt.Errorf("shrek: could not marshal public key: %w", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// ParsePEM parses a PEM encoded Ed25519 key. It accepts a PKCS#8 "PRIVATE KEY" block,
+// in which case the secret key is derived from the seed inside it, or a PKIX "PUBLIC
+// KEY" block, in which case the returned address has no secret key.
+func ParsePEM(data []byte) (*OnionAddress, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("shrek: no PEM block found")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("shrek: could not parse PKCS#8 private key: %w", err)
+		}
+
+		sk, ok := key.(stded25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("shrek: PKCS#8 private key is not an Ed25519 key: %T", key)
+		}
+		return NewOnionAddressFromSeed(sk.Seed())
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("shrek: could not parse public key: %w", err)
+		}
+
+		pk, ok := key.(stded25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("shrek: public key is not an Ed25519 key: %T", key)
+		}
+		return &OnionAddress{PublicKey: ed25519.PublicKey(pk)}, nil
+	default:
+		return nil, fmt.Errorf("shrek: unsupported PEM block type: %q", block.Type)
+	}
+}
+
+type onionAddressJSON struct {
+	HostName  string `json:"hostname"`
+	PublicKey []byte `json:"public_key"`
+	SecretKey []byte `json:"secret_key,omitempty"`
+}
+
+// MarshalJSON encodes the address as a JSON object with the hostname and the base64
+// encoded keys. Beware that the secret key is included if the address has one.
+func (addr *OnionAddress) MarshalJSON() ([]byte, error) {
+	return json.Marshal(onionAddressJSON{
+		HostName:  addr.HostNameString(),
+		PublicKey: addr.PublicKey,
+		SecretKey: addr.SecretKey,
+	})
+}
+
+// UnmarshalJSON decodes an address encoded by MarshalJSON. The keys are validated,
+// and if a hostname is present it must match the keys.
+func (addr *OnionAddress) UnmarshalJSON(data []byte) error {
+	var v onionAddressJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	var parsed *OnionAddress
+	var err error
+	if len(v.SecretKey) > 0 {
+		parsed, err = newOnionAddressFromSecretKey(v.SecretKey)
+		if err == nil && len(v.PublicKey) > 0 && !bytes.Equal(parsed.PublicKey, v.PublicKey) {
+			err = errors.New("shrek: public key does not match secret key")
+		}
+	} else {
+		if l := len(v.PublicKey); l != ed25519.PublicKeySize {
+			return fmt.Errorf("shrek: public key has wrong length: %d", l)
+		}
+		parsed = &OnionAddress{PublicKey: ed25519.PublicKey(v.PublicKey)}
+	}
+	if err != nil {
+		return err
+	}
+
+	if hostname := parsed.HostNameString(); v.HostName != "" && v.HostName != hostname {
+		return fmt.Errorf("shrek: hostname %q does not match keys, expected %q", v.HostName, hostname)
+	}
+
+	*addr = *parsed
+	return nil
+}
+
+func newOnionAddressFromSecretKey(sk []byte) (*OnionAddress, error) {
+	kp, err := ed25519.NewKeyPair(ed25519.PrivateKey(sk))
+	if err != nil {
+		return nil, fmt.Errorf("shrek: secret key is not valid: %w", err)
+	}
+
+	return &OnionAddress{
+		PublicKey: kp.PublicKey,
+		SecretKey: kp.PrivateKey,
+	}, nil
+}
+
+// parseKeyFile checks the header and length of one of Tor's key files, then returns
+// the key data that follows the header.
+func parseKeyFile(data []byte, header string, keySize int, kind string) ([]byte, error) {
+	if l := len(data); l != len(header)+keySize {
+		return nil, fmt.Errorf("shrek: %s key file has wrong length: %d", kind, l)
+	}
+	if !bytes.HasPrefix(data, []byte(header)) {
+		return nil, fmt.Errorf("shrek: %s key file has wrong header: %q", kind, data[:len(header)])
+	}
+
+	return data[len(header):], nil
+}
@@ -0,0 +1,162 @@
+package shrek_test
+
+import (
+	"bytes"
+	stded25519 "crypto/ed25519"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"github.com/innix/shrek"
+)
+
+func TestOnionAddress_SecretKeyFile_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	addr, err := shrek.GenerateOnionAddress(bytes.NewBufferString(seed))
+	if err != nil {
+		t.Fatalf("could not generate the prerequisite onion address: %v", err)
+	}
+
+	data, err := addr.SecretKeyFile()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := shrek.ParseSecretKeyFile(data)
+	if err != nil {
+		t.Fatalf("could not parse secret key file: %v", err)
+	}
+	assertSameAddress(t, got, addr)
+
+	if _, err := shrek.ParseSecretKeyFile(addr.PublicKeyFile()); err == nil {
+		t.Errorf("expected error when parsing public key file as secret key file, got nil")
+	}
+
+	_, err = (&shrek.OnionAddress{PublicKey: addr.PublicKey}).SecretKeyFile()
+	if !errors.Is(err, shrek.ErrNoSecretKey) {
+		t.Errorf("expected ErrNoSecretKey, got: %v", err)
+	}
+}
+
+func TestOnionAddress_ControlPortKey_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	addr, err := shrek.GenerateOnionAddress(bytes.NewBufferString(seed))
+	if err != nil {
+		t.Fatalf("could not generate the prerequisite onion address: %v", err)
+	}
+
+	key, err := addr.ControlPortKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := shrek.ParseControlPortKey(key)
+	if err != nil {
+		t.Fatalf("could not parse control port key: %v", err)
+	}
+	assertSameAddress(t, got, addr)
+
+	for _, bad := range []string{"", "RSA1024:abcd", "ED25519-V3:not-base64!", "ED25519-V3:AAAA"} {
+		if _, err := shrek.ParseControlPortKey(bad); err == nil {
+			t.Errorf("expected error for %q, got nil", bad)
+		}
+	}
+}
+
+func TestOnionAddress_JSON_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	addr, err := shrek.GenerateOnionAddress(bytes.NewBufferString(seed))
+	if err != nil {
+		t.Fatalf("could not generate the prerequisite onion address: %v", err)
+	}
+
+	data, err := json.Marshal(addr)
+	if err != nil {
+		t.Fatalf("could not marshal onion address: %v", err)
+	}
+
+	var got shrek.OnionAddress
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("could not unmarshal onion address: %v", err)
+	}
+	assertSameAddress(t, &got, addr)
+
+	// Public key only.
+	data, err = json.Marshal(&shrek.OnionAddress{PublicKey: addr.PublicKey})
+	if err != nil {
+		t.Fatalf("could not marshal onion address: %v", err)
+	}
+
+	var pub shrek.OnionAddress
+	if err := json.Unmarshal(data, &pub); err != nil {
+		t.Fatalf("could not unmarshal onion address: %v", err)
+	}
+	if pub.SecretKey != nil || !bytes.Equal(pub.PublicKey, addr.PublicKey) {
+		t.Errorf("unexpected public-only address: %+v", pub)
+	}
+
+	// Hostname that doesn't match the keys.
+	bad := []byte(`{"hostname": "` + seedHostname[:55] + `a.onion", "public_key": "QX2jmVeHo/FuS7QAnZTp8hvwiERDTwz9s/px4av/Nbk="}`)
+	if err := json.Unmarshal(bad, &pub); err == nil {
+		t.Errorf("expected error for mismatched hostname, got nil")
+	}
+}
+
+func TestParsePEM(t *testing.T) {
+	t.Parallel()
+
+	stdSeed := []byte(seed[:stded25519.SeedSize])
+	stdKey := stded25519.NewKeyFromSeed(stdSeed)
+
+	want, err := shrek.NewOnionAddressFromSeed(stdSeed)
+	if err != nil {
+		t.Fatalf("could not create onion address from seed: %v", err)
+	}
+	if !bytes.Equal(want.PublicKey, stdKey.Public().(stded25519.PublicKey)) {
+		t.Fatalf("public key derived from seed does not match crypto/ed25519")
+	}
+
+	// Private key.
+	der, err := x509.MarshalPKCS8PrivateKey(stdKey)
+	if err != nil {
+		t.Fatalf("could not marshal PKCS#8 private key: %v", err)
+	}
+	got, err := shrek.ParsePEM(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("could not parse PKCS#8 PEM: %v", err)
+	}
+	assertSameAddress(t, got, want)
+
+	// Public key round-trip.
+	pubPEM, err := want.PublicKeyPEM()
+	if err != nil {
+		t.Fatalf("could not marshal public key PEM: %v", err)
+	}
+	pub, err := shrek.ParsePEM(pubPEM)
+	if err != nil {
+		t.Fatalf("could not parse public key PEM: %v", err)
+	}
+	if pub.SecretKey != nil || !bytes.Equal(pub.PublicKey, want.PublicKey) {
+		t.Errorf("unexpected address from public key PEM: %+v", pub)
+	}
+
+	if _, err := shrek.ParsePEM([]byte("not a pem")); err == nil {
+		t.Errorf("expected error for non-PEM data, got nil")
+	}
+}
+
+func assertSameAddress(t *testing.T, got, want *shrek.OnionAddress) {
+	t.Helper()
+
+	if !bytes.Equal(got.PublicKey, want.PublicKey) {
+		t.Errorf("unexpected public key, got: %v, wanted: %v", got.PublicKey, want.PublicKey)
+	}
+	if !bytes.Equal(got.SecretKey, want.SecretKey) {
+		t.Errorf("unexpected secret key, got: %v, wanted: %v", got.SecretKey, want.SecretKey)
+	}
+}
@@ -0,0 +1,187 @@
+// Package keyiter generates sequences of Ed25519 key pairs quickly, for programs that
+// want to run their own search for vanity onion addresses instead of using the miner in
+// the shrek package.
+//
+// Generating an Ed25519 key pair the normal way needs a SHA-512 hash and a scalar
+// multiplication for every key. The Iterator avoids both. It generates one random key
+// pair, with private scalar a and public key A = aB, where B is the Ed25519 basepoint.
+// Then each call to Next steps to the next key in the sequence:
+//
+//   a(n) = a + 8n
+//   A(n) = A + n(8B)
+//
+// so getting the next public key costs a single point addition. Encoding a point as a
+// public key needs a field inversion, which is the expensive part, so the Iterator works
+// out public keys in batches that share one inversion. The private key of a public key
+// is only computed when it's asked for, which a search only has to do once it finds a
+// match.
+//
+// The step is 8, not 1, so that every private scalar in the sequence stays clamped.
+// Ed25519 requires the lowest 3 bits of the scalar to be zero, which makes it a multiple
+// of the curve's cofactor; adding a multiple of 8 keeps it that way, so every key is a
+// valid key that Tor and other Ed25519 implementations accept.
+//
+// The keys in a sequence are not independent of each other, so the following should be
+// kept in mind:
+//
+//   - Anyone who learns one private key of a sequence, and its counter, can work out
+//     every other key in the sequence, because they're only 8 apart. Keep only the key
+//     you need, and call Destroy on the iterator when you're done with it.
+//
+//   - Every key in a sequence shares the second half of its expanded private key, which
+//     Ed25519 uses to derive signature nonces. If 2 keys from the same sequence sign the
+//     same message, the private keys can be recovered from the signatures. Never use
+//     more than 1 key from a sequence.
+//
+//   - A State holds the starting private key of the sequence, so it's as secret as the
+//     keys themselves.
+//
+//   - The public keys don't reveal the private keys. But they can be linked: the
+//     difference between 2 public keys from the same sequence is a small multiple of
+//     8B, which can be checked for. Don't use an iterator to generate keys that must
+//     not be tied to each other.
+//
+// The private keys are returned in their 64 byte expanded form, the same as the
+// OnionAddress.SecretKey field in the shrek package expects. There's no seed that they
+// can be derived from, so they can't be converted to the 32 byte seed form used by
+// crypto/ed25519.
+package keyiter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/innix/shrek/internal/ed25519"
+)
+
+const (
+	// PublicKeySize is the size, in bytes, of the public keys returned by PublicKey.
+	PublicKeySize = ed25519.PublicKeySize
+
+	// PrivateKeySize is the size, in bytes, of the expanded private keys returned by
+	// PrivateKey.
+	PrivateKeySize = ed25519.PrivateKeySize
+
+	// Step is how much the counter of an Iterator is incremented by on each call to
+	// Next. Counters given to Resume must be a multiple of it.
+	Step = 8
+
+	// StateSize is the size, in bytes, of a State encoded by MarshalBinary.
+	StateSize = PrivateKeySize + 8
+)
+
+// Iterator generates a sequence of Ed25519 key pairs, as described in the package
+// documentation. It isn't safe for concurrent use; create an Iterator for each
+// goroutine instead.
+type Iterator struct {
+	it *ed25519.KeyIterator
+}
+
+// New creates an Iterator that starts from a random key pair. If rand is nil,
+// crypto/rand is used.
+func New(rand io.Reader) (*Iterator, error) {
+	it, err := ed25519.NewKeyIterator(rand)
+	if err != nil {
+		return nil, fmt.Errorf("keyiter: could not create iterator: %w", err)
+	}
+
+	return &Iterator{it: it}, nil
+}
+
+// Resume creates an Iterator from a state returned by Iterator.State, so that it carries
+// on from where that iterator was.
+func Resume(s State) (*Iterator, error) {
+	it, err := ed25519.NewKeyIteratorFromKey(s.StartKey, s.Counter)
+	if err != nil {
+		return nil, fmt.Errorf("keyiter: could not resume iterator: %w", err)
+	}
+
+	return &Iterator{it: it}, nil
+}
+
+// Next steps the iterator on to the next key pair in the sequence. It returns false if
+// the sequence has been exhausted, which won't happen in practice.
+func (it *Iterator) Next() bool {
+	return it.it.Next()
+}
+
+// PublicKey returns the 32 byte public key of the current key pair.
+func (it *Iterator) PublicKey() []byte {
+	return it.it.PublicKey()
+}
+
+// PrivateKey returns the 64 byte expanded private key of the current key pair. It's much
+// slower than PublicKey, so should only be called for the keys that are kept.
+func (it *Iterator) PrivateKey() ([]byte, error) {
+	sk, err := it.it.PrivateKey()
+	if err != nil {
+		return nil, fmt.Errorf("keyiter: could not compute private key: %w", err)
+	}
+
+	return sk, nil
+}
+
+// Counter returns how far the iterator has stepped from its starting key. It's
+// incremented by Step on each call to Next.
+func (it *Iterator) Counter() uint64 {
+	return it.it.Counter()
+}
+
+// State returns the state of the iterator, which can be passed to Resume to carry on
+// from the current key pair later, e.g. after a restart. The state is secret; see the
+// package documentation.
+func (it *Iterator) State() State {
+	return State{
+		StartKey: it.it.StartKey(),
+		Counter:  it.it.Counter(),
+	}
+}
+
+// Destroy wipes the secret key material held by the iterator. The iterator must not be
+// used afterwards. Keys already returned by PrivateKey are left alone.
+func (it *Iterator) Destroy() {
+	it.it.Destroy()
+}
+
+// State is the state of an Iterator, as returned by Iterator.State.
+type State struct {
+	// StartKey is the 64 byte expanded private key that the iterator started from.
+	StartKey []byte
+
+	// Counter is how far the iterator had stepped from StartKey. It must be a multiple
+	// of Step.
+	Counter uint64
+}
+
+// MarshalBinary encodes the state as StartKey followed by Counter, as an 8 byte
+// big-endian integer.
+func (s State) MarshalBinary() ([]byte, error) {
+	if l := len(s.StartKey); l != PrivateKeySize {
+		return nil, fmt.Errorf("keyiter: start key has wrong length: %d", l)
+	}
+
+	data := make([]byte, StateSize)
+	copy(data, s.StartKey)
+	binary.BigEndian.PutUint64(data[PrivateKeySize:], s.Counter)
+
+	return data, nil
+}
+
+// UnmarshalBinary decodes a state encoded by MarshalBinary.
+func (s *State) UnmarshalBinary(data []byte) error {
+	if l := len(data); l != StateSize {
+		return fmt.Errorf("keyiter: state has wrong length: %d", l)
+	}
+
+	s.StartKey = append([]byte(nil), data[:PrivateKeySize]...)
+	s.Counter = binary.BigEndian.Uint64(data[PrivateKeySize:])
+
+	return nil
+}
+
+// Destroy wipes the start key held by the state.
+func (s *State) Destroy() {
+	ed25519.Wipe(s.StartKey)
+	s.StartKey = nil
+}
@@ -0,0 +1,101 @@
+package keyiter_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/innix/shrek"
+	"github.com/innix/shrek/keyiter"
+)
+
+func TestIterator(t *testing.T) {
+	t.Parallel()
+
+	it, err := keyiter.New(nil)
+	if err != nil {
+		t.Fatalf("could not create iterator: %v", err)
+	}
+	defer it.Destroy()
+
+	seen := make(map[string]bool)
+	for i := 0; i < 16; i++ {
+		if c := it.Counter(); c != uint64(i*keyiter.Step) {
+			t.Fatalf("unexpected counter, got: %d, wanted: %d", c, i*keyiter.Step)
+		}
+
+		pk := it.PublicKey()
+		if seen[string(pk)] {
+			t.Fatalf("iterator repeated a public key")
+		}
+		seen[string(pk)] = true
+
+		sk, err := it.PrivateKey()
+		if err != nil {
+			t.Fatalf("could not get private key: %v", err)
+		}
+		assertKeyPair(t, pk, sk)
+
+		if !it.Next() {
+			t.Fatalf("iterator stopped early")
+		}
+	}
+}
+
+func TestResume(t *testing.T) {
+	t.Parallel()
+
+	it, err := keyiter.New(nil)
+	if err != nil {
+		t.Fatalf("could not create iterator: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		it.Next()
+	}
+
+	data, err := it.State().MarshalBinary()
+	if err != nil {
+		t.Fatalf("could not marshal state: %v", err)
+	}
+	var state keyiter.State
+	if err := state.UnmarshalBinary(data); err != nil {
+		t.Fatalf("could not unmarshal state: %v", err)
+	}
+
+	resumed, err := keyiter.Resume(state)
+	if err != nil {
+		t.Fatalf("could not resume iterator: %v", err)
+	}
+	if resumed.Counter() != it.Counter() {
+		t.Errorf("unexpected counter, got: %d, wanted: %d", resumed.Counter(), it.Counter())
+	}
+
+	for i := 0; i < 4; i++ {
+		if !bytes.Equal(resumed.PublicKey(), it.PublicKey()) {
+			t.Fatalf("resumed iterator produced a different public key at step %d", i)
+		}
+		it.Next()
+		resumed.Next()
+	}
+
+	state.Counter++
+	if _, err := keyiter.Resume(state); err == nil {
+		t.Errorf("expected error resuming with a counter that isn't a multiple of %d", keyiter.Step)
+	}
+	if err := state.UnmarshalBinary(data[1:]); err == nil {
+		t.Errorf("expected error unmarshalling truncated state")
+	}
+}
+
+// assertKeyPair checks that sk is the private key of pk by signing with it.
+func assertKeyPair(t *testing.T, pk, sk []byte) {
+	t.Helper()
+
+	addr := &shrek.OnionAddress{PublicKey: pk, SecretKey: sk}
+	sig, err := addr.Sign([]byte("shrek"))
+	if err != nil {
+		t.Fatalf("could not sign with private key: %v", err)
+	}
+	if !shrek.Verify(pk, []byte("shrek"), sig) {
+		t.Errorf("private key does not match public key")
+	}
+}
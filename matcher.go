@@ -3,7 +3,12 @@ package shrek
 import (
 	"bytes"
 	"fmt"
+	"math"
+	"regexp"
+	"sort"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type Matcher interface {
@@ -25,18 +30,24 @@ func (m StartEndMatcher) Match(exact []byte) bool {
 }
 
 func (m StartEndMatcher) Validate() error {
-	const validRunes = "abcdefghijklmnopqrstuvwxyz234567"
-	const maxLength = 56
+	const maxLength = EncodedPublicKeySize
 
 	// Check filter length isn't too long.
 	if l := len(m.Start) + len(m.End); l > maxLength {
-		return fmt.Errorf("shrek: filter is too long (%d > %d)", l, maxLength)
+		return &PatternError{
+			Part:   PatternPartNone,
+			Index:  -1,
+			Reason: fmt.Sprintf("filter is too long (%d > %d)", l, maxLength),
+		}
 	}
 
-	if len(m.Start) > 0 {
-		// Check for invalid chars in Start.
-		if invalid := strings.Trim(string(m.Start), validRunes); invalid != "" {
-			return fmt.Errorf("shrek: start part contains invalid chars: %q", invalid)
+	// Check for invalid chars in Start.
+	if i, c := invalidChar(m.Start); i != -1 {
+		return &PatternError{
+			Part:   PatternPartStart,
+			Index:  i,
+			Char:   c,
+			Reason: fmt.Sprintf("start part contains invalid char %q at position %d", string(c), i),
 		}
 	}
 
@@ -47,25 +58,166 @@ func (m StartEndMatcher) Validate() error {
 	}
 
 	// Check for invalid chars in End.
-	if invalid := strings.Trim(string(m.End), validRunes); invalid != "" {
-		return fmt.Errorf("shrek: end part contains invalid chars: %q", invalid)
+	if i, c := invalidChar(m.End); i != -1 {
+		return &PatternError{
+			Part:        PatternPartEnd,
+			Index:       i,
+			Char:        c,
+			Reason:      fmt.Sprintf("end part contains invalid char %q at position %d", string(c), i),
+			Suggestions: SuggestEnds(m.End),
+		}
 	}
 
 	// If last char isn't "d".
-	if chr := string(m.End[len(m.End)-1]); chr != "d" {
-		return fmt.Errorf("shrek: last char in end part must be %q, not %q", "d", chr)
+	if i := len(m.End) - 1; m.End[i] != 'd' {
+		return &PatternError{
+			Part:        PatternPartEnd,
+			Index:       i,
+			Char:        rune(m.End[i]),
+			Reason:      fmt.Sprintf("last char in end part must be %q, not %q", "d", string(m.End[i])),
+			Suggestions: SuggestEnds(m.End),
+		}
 	}
 
 	if len(m.End) > 1 {
 		// If 2nd last char isn't any of "aiqy".
-		if chr := string(m.End[len(m.End)-2]); strings.Trim(chr, "aiqy") != "" {
-			return fmt.Errorf("shrek: 2nd last char in end part must be one of %q, not %q", "aiqy", chr)
+		if i := len(m.End) - 2; !strings.ContainsRune("aiqy", rune(m.End[i])) {
+			return &PatternError{
+				Part:        PatternPartEnd,
+				Index:       i,
+				Char:        rune(m.End[i]),
+				Reason:      fmt.Sprintf("2nd last char in end part must be one of %q, not %q", "aiqy", string(m.End[i])),
+				Suggestions: SuggestEnds(m.End),
+			}
 		}
 	}
 
 	return nil
 }
 
+// Difficulty describes how many bits of an address a StartEndMatcher constrains, and
+// which of those bits can be checked cheaply.
+//
+// An onion address encodes the 32 byte public key, followed by a 2 byte checksum and a
+// 1 byte version. The first 51 chars only depend on the public key, so they can be
+// checked with the fast approximate encoder. The last 5 chars also depend on the
+// checksum and version, so they can only be checked after the much slower exact
+// encoder has run, which includes computing a SHA3 hash.
+type Difficulty struct {
+	// PublicKeyBits is the number of public key bits constrained by the pattern.
+	PublicKeyBits int
+
+	// ChecksumBits is the number of checksum bits constrained by the pattern.
+	ChecksumBits int
+
+	// FixedBits is the number of version bits constrained by the pattern. These are
+	// the same for every address, so they don't make the search any harder.
+	FixedBits int
+
+	// ExactOnlyBits is the number of constrained bits that can only be checked with
+	// the exact encoder. It includes all of ChecksumBits.
+	ExactOnlyBits int
+}
+
+// ExpectedAttempts returns the average number of keys that need to be tried to find
+// a matching address.
+func (d Difficulty) ExpectedAttempts() float64 {
+	return math.Exp2(float64(d.PublicKeyBits + d.ChecksumBits))
+}
+
+// ExpectedExactEncodings returns the average number of keys that pass the approximate
+// check, and so need to be exactly encoded, for each matching address found.
+func (d Difficulty) ExpectedExactEncodings() float64 {
+	return math.Exp2(float64(d.ExactOnlyBits))
+}
+
+// Difficulty returns how many bits of an address the matcher constrains. The result
+// is only meaningful if Validate returns nil.
+func (m StartEndMatcher) Difficulty() Difficulty {
+	var d Difficulty
+
+	addChar := func(pos int) {
+		const (
+			publicKeyBits = 256
+			checksumBits  = 16
+		)
+
+		for bit := pos * 5; bit < (pos+1)*5; bit++ {
+			switch {
+			case bit < publicKeyBits:
+				d.PublicKeyBits++
+			case bit < publicKeyBits+checksumBits:
+				d.ChecksumBits++
+			default:
+				d.FixedBits++
+			}
+
+			if pos >= EncodedPublicKeyApproxSize && bit < publicKeyBits+checksumBits {
+				d.ExactOnlyBits++
+			}
+		}
+	}
+
+	for i := range m.Start {
+		addChar(i)
+	}
+	for i := range m.End {
+		addChar(EncodedPublicKeySize - len(m.End) + i)
+	}
+
+	return d
+}
+
+// SuggestEnds returns valid end parts that are close to the given end part, closest
+// first. Common lookalike chars that aren't allowed in an address are swapped for
+// allowed ones (e.g. "0" becomes "o"), the last char is replaced with "d" and, if
+// needed, the 2nd last char is replaced with one of "aiqy". It returns nil if end is
+// empty or no suggestion could be made.
+func SuggestEnds(end []byte) []string {
+	const maxSuggestions = 4
+
+	lookalikes := map[byte]byte{'0': 'o', '1': 'l', '8': 'b', '9': 'g'}
+
+	fixed := make([]byte, 0, len(end))
+	for _, c := range end {
+		c = byte(unicode.ToLower(rune(c)))
+		if r, ok := lookalikes[c]; ok {
+			c = r
+		}
+		if !isInvalidRune(rune(c)) {
+			fixed = append(fixed, c)
+		}
+	}
+	if len(fixed) == 0 {
+		return nil
+	}
+	fixed[len(fixed)-1] = 'd'
+
+	if len(fixed) == 1 {
+		return []string{string(fixed)}
+	}
+
+	// Try the valid 2nd last chars in order of how close they are to the original one.
+	// If the original one is already valid, then it's the closest.
+	orig := fixed[len(fixed)-2]
+	candidates := []byte("aiqy")
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return absInt(int(candidates[i])-int(orig)) < absInt(int(candidates[j])-int(orig))
+	})
+
+	var suggestions []string
+	for _, c := range candidates {
+		fixed[len(fixed)-2] = c
+		suggestions = append(suggestions, string(fixed))
+	}
+
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+
+	return suggestions
+}
+
 type MultiMatcher struct {
 	Inner []Matcher
 
@@ -97,3 +249,295 @@ func (m MultiMatcher) Match(exact []byte) bool {
 
 	return m.All
 }
+
+// MatchingInner returns the first of the Inner matchers that matches the exact
+// hostname, or nil if none of them match.
+func (m MultiMatcher) MatchingInner(exact []byte) Matcher {
+	for _, im := range m.Inner {
+		if im.MatchApprox(exact) && im.Match(exact) {
+			return im
+		}
+	}
+
+	return nil
+}
+
+// ContainsMatcher matches addresses that contain Text anywhere in them.
+type ContainsMatcher struct {
+	Text []byte
+}
+
+func (m ContainsMatcher) MatchApprox(approx []byte) bool {
+	if bytes.Contains(approx[:EncodedPublicKeyApproxSize], m.Text) {
+		return true
+	}
+
+	// The text could still be in the last few chars of the address, which the approx
+	// encoding gets wrong. So check every place the text could start that runs on into
+	// the inaccurate part: the chars in the accurate part must match, and the rest must
+	// fit the chars that are fixed at the end of every address.
+	start := EncodedPublicKeyApproxSize - len(m.Text) + 1
+	if start < 0 {
+		start = 0
+	}
+	for i := start; i+len(m.Text) <= EncodedPublicKeySize; i++ {
+		n := EncodedPublicKeyApproxSize - i
+		if n < 0 {
+			n = 0
+		}
+		if !bytes.HasPrefix(m.Text, approx[i:i+n]) {
+			continue
+		}
+		if fitsAddressTail(m.Text[n:], i+n, approx[EncodedPublicKeyApproxSize]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fitsAddressTail reports whether text could appear at position pos of an address, where
+// pos is in the inaccurate part at the end of the approx encoding. The 1st char of that
+// part holds the last bit of the public key, so its top bit is the same as in the approx
+// char given. The last char of an address is always "d", and the 2nd last is always one
+// of "aiqy". The chars in between are all checksum, so can be anything.
+func fitsAddressTail(text []byte, pos int, approx byte) bool {
+	for i, c := range text {
+		switch pos + i {
+		case EncodedPublicKeyApproxSize:
+			if strings.IndexByte(base32Alphabet, c)>>4 != strings.IndexByte(base32Alphabet, approx)>>4 {
+				return false
+			}
+		case EncodedPublicKeySize - 2:
+			if !strings.ContainsRune("aiqy", rune(c)) {
+				return false
+			}
+		case EncodedPublicKeySize - 1:
+			if c != 'd' {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func (m ContainsMatcher) Match(exact []byte) bool {
+	return bytes.Contains(exact, m.Text)
+}
+
+func (m ContainsMatcher) Validate() error {
+	const maxLength = EncodedPublicKeySize
+
+	if len(m.Text) == 0 {
+		return &PatternError{
+			Part:   PatternPartNone,
+			Index:  -1,
+			Reason: "contains text must not be empty",
+		}
+	} else if l := len(m.Text); l > maxLength {
+		return &PatternError{
+			Part:   PatternPartNone,
+			Index:  -1,
+			Reason: fmt.Sprintf("contains text is too long (%d > %d)", l, maxLength),
+		}
+	}
+
+	if i, c := invalidChar(m.Text); i != -1 {
+		return &PatternError{
+			Part:   PatternPartNone,
+			Index:  i,
+			Char:   c,
+			Reason: fmt.Sprintf("contains text has invalid char %q at position %d", string(c), i),
+		}
+	}
+
+	return nil
+}
+
+// RegexMatcher matches addresses using a regular expression. The regular expression
+// can't be evaluated against an approximate encoding, so every key is fully encoded;
+// combine it with a faster matcher using a MultiMatcher with All set to true when
+// possible.
+type RegexMatcher struct {
+	Regexp *regexp.Regexp
+}
+
+func (m RegexMatcher) MatchApprox(approx []byte) bool {
+	return true
+}
+
+func (m RegexMatcher) Match(exact []byte) bool {
+	return m.Regexp.Match(exact)
+}
+
+// ExcludeMatcher matches addresses that are matched by Inner but not by any of the
+// Exclude matchers.
+type ExcludeMatcher struct {
+	Inner   Matcher
+	Exclude []Matcher
+}
+
+func (m ExcludeMatcher) MatchApprox(approx []byte) bool {
+	// Exclusions can only be ruled out with an exact encoding.
+	return m.Inner.MatchApprox(approx)
+}
+
+func (m ExcludeMatcher) Match(exact []byte) bool {
+	if !m.Inner.Match(exact) {
+		return false
+	}
+
+	for _, em := range m.Exclude {
+		if em.MatchApprox(exact) && em.Match(exact) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RepeatMatcher matches addresses that start with Length identical characters,
+// e.g. "aaaaaa".
+type RepeatMatcher struct {
+	Length int
+}
+
+func (m RepeatMatcher) MatchApprox(approx []byte) bool {
+	return isRepeat(approx[:minInt(m.Length, EncodedPublicKeyApproxSize)])
+}
+
+func (m RepeatMatcher) Match(exact []byte) bool {
+	return len(exact) >= m.Length && isRepeat(exact[:m.Length])
+}
+
+func (m RepeatMatcher) Validate() error {
+	return validateStructuralLength("repeat", m.Length)
+}
+
+// PalindromeMatcher matches addresses where the first Length characters read the
+// same backwards as forwards, e.g. "abcba".
+type PalindromeMatcher struct {
+	Length int
+}
+
+func (m PalindromeMatcher) MatchApprox(approx []byte) bool {
+	// Only compare the pairs of chars where both chars are accurate in the approx
+	// encoding. The rest are checked in Match.
+	for i, j := 0, m.Length-1; i < j; i, j = i+1, j-1 {
+		if j < EncodedPublicKeyApproxSize && approx[i] != approx[j] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (m PalindromeMatcher) Match(exact []byte) bool {
+	if len(exact) < m.Length {
+		return false
+	}
+
+	for i, j := 0, m.Length-1; i < j; i, j = i+1, j-1 {
+		if exact[i] != exact[j] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (m PalindromeMatcher) Validate() error {
+	return validateStructuralLength("palindrome", m.Length)
+}
+
+// AlphaMatcher matches addresses where the first Length characters are all letters,
+// i.e. they contain none of the digits "234567".
+type AlphaMatcher struct {
+	Length int
+}
+
+func (m AlphaMatcher) MatchApprox(approx []byte) bool {
+	return isAlpha(approx[:minInt(m.Length, EncodedPublicKeyApproxSize)])
+}
+
+func (m AlphaMatcher) Match(exact []byte) bool {
+	return len(exact) >= m.Length && isAlpha(exact[:m.Length])
+}
+
+func (m AlphaMatcher) Validate() error {
+	return validateStructuralLength("alpha", m.Length)
+}
+
+func validateStructuralLength(name string, length int) error {
+	const maxLength = EncodedPublicKeySize
+
+	if length < 1 {
+		return &PatternError{
+			Part:   PatternPartLength,
+			Index:  -1,
+			Reason: fmt.Sprintf("%s length must be at least 1, not %d", name, length),
+		}
+	} else if length > maxLength {
+		return &PatternError{
+			Part:   PatternPartLength,
+			Index:  -1,
+			Reason: fmt.Sprintf("%s length is too long (%d > %d)", name, length, maxLength),
+		}
+	}
+
+	return nil
+}
+
+func isRepeat(b []byte) bool {
+	for i := 1; i < len(b); i++ {
+		if b[i] != b[0] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// invalidChar returns the byte position of the first char in b that isn't in the base32
+// alphabet, and that char, or -1 if there isn't one. The alphabet is ASCII, so a byte
+// loop finds it, but the char is decoded as UTF-8 so a multibyte char is reported whole.
+func invalidChar(b []byte) (int, rune) {
+	for i, c := range b {
+		if isInvalidRune(rune(c)) {
+			r, _ := utf8.DecodeRune(b[i:])
+			return i, r
+		}
+	}
+
+	return -1, 0
+}
+
+func isInvalidRune(r rune) bool {
+	const validRunes = "abcdefghijklmnopqrstuvwxyz234567"
+	return !strings.ContainsRune(validRunes, r)
+}
+
+func isAlpha(b []byte) bool {
+	for _, c := range b {
+		if c < 'a' || c > 'z' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func absInt(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
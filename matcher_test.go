@@ -1,6 +1,7 @@
 package shrek_test
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
 	"testing"
@@ -194,6 +195,244 @@ func TestStartEndMatcher_Valid(t *testing.T) {
 	}
 }
 
+func TestRepeatMatcher_Match(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		Input  string
+		Length int
+		Match  bool
+	}{
+		{Input: "aaaaaajsviqu5fqvqzv5mnfonrapka477vonf6fuko7duolp5g3iad", Length: 6, Match: true},
+		{Input: "aaaaaajsviqu5fqvqzv5mnfonrapka477vonf6fuko7duolp5g3iad", Length: 1, Match: true},
+		{Input: "77777ajsviqu5fqvqzv5mnfonrapka477vonf6fuko7duolp5g3iad", Length: 5, Match: true},
+		{Input: "aaaaaajsviqu5fqvqzv5mnfonrapka477vonf6fuko7duolp5g3iad", Length: 7, Match: false},
+		{Input: "abaaaajsviqu5fqvqzv5mnfonrapka477vonf6fuko7duolp5g3iad", Length: 2, Match: false},
+	}
+
+	for _, tc := range table {
+		tc := tc
+		name := fmt.Sprintf("repeat:%d=%s", tc.Length, tc.Input)
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			m := shrek.RepeatMatcher{Length: tc.Length}
+			input := []byte(tc.Input)
+
+			if match := m.MatchApprox(input) && m.Match(input); match != tc.Match {
+				t.Errorf("invalid match result: got %v, wanted %v", match, tc.Match)
+			}
+		})
+	}
+}
+
+func TestPalindromeMatcher_Match(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		Input  string
+		Length int
+		Match  bool
+	}{
+		{Input: "abcbajsviqu5fqvqzv5mnfonrapka477vonf6fuko7duolp5g3iad", Length: 5, Match: true},
+		{Input: "ab2ba7sviqu5fqvqzv5mnfonrapka477vonf6fuko7duolp5g3iad", Length: 5, Match: true},
+		{Input: "abbajjsviqu5fqvqzv5mnfonrapka477vonf6fuko7duolp5g3iad", Length: 4, Match: true},
+		{Input: "abcbajsviqu5fqvqzv5mnfonrapka477vonf6fuko7duolp5g3iad", Length: 1, Match: true},
+		{Input: "abcbajsviqu5fqvqzv5mnfonrapka477vonf6fuko7duolp5g3iad", Length: 6, Match: false},
+		{Input: "abcdajsviqu5fqvqzv5mnfonrapka477vonf6fuko7duolp5g3iad", Length: 5, Match: false},
+	}
+
+	for _, tc := range table {
+		tc := tc
+		name := fmt.Sprintf("palindrome:%d=%s", tc.Length, tc.Input)
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			m := shrek.PalindromeMatcher{Length: tc.Length}
+			input := []byte(tc.Input)
+
+			if match := m.MatchApprox(input) && m.Match(input); match != tc.Match {
+				t.Errorf("invalid match result: got %v, wanted %v", match, tc.Match)
+			}
+		})
+	}
+}
+
+func TestAlphaMatcher_Match(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		Input  string
+		Length int
+		Match  bool
+	}{
+		{Input: "abcdyjsviqu5fqvqzv5mnfonrapka477vonf6fuko7duolp5g3iad", Length: 11, Match: true},
+		{Input: "abcdyjsviqu5fqvqzv5mnfonrapka477vonf6fuko7duolp5g3iad", Length: 1, Match: true},
+		{Input: "abcdyjsviqu5fqvqzv5mnfonrapka477vonf6fuko7duolp5g3iad", Length: 12, Match: false},
+		{Input: "2bcdyjsviqu5fqvqzv5mnfonrapka477vonf6fuko7duolp5g3iad", Length: 1, Match: false},
+	}
+
+	for _, tc := range table {
+		tc := tc
+		name := fmt.Sprintf("alpha:%d=%s", tc.Length, tc.Input)
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			m := shrek.AlphaMatcher{Length: tc.Length}
+			input := []byte(tc.Input)
+
+			if match := m.MatchApprox(input) && m.Match(input); match != tc.Match {
+				t.Errorf("invalid match result: got %v, wanted %v", match, tc.Match)
+			}
+		})
+	}
+}
+
+func TestContainsMatcher_MatchApprox(t *testing.T) {
+	t.Parallel()
+
+	// The approx matcher must never reject an address that the exact matcher accepts.
+	for i := 0; i < 1000; i++ {
+		addr, err := shrek.GenerateOnionAddress(nil)
+		if err != nil {
+			t.Fatalf("could not generate onion address: %v", err)
+		}
+
+		exact := make([]byte, shrek.EncodedPublicKeySize)
+		addr.HostName(exact)
+		approx := make([]byte, shrek.EncodedPublicKeySize)
+		addr.HostNameApprox(approx)
+
+		for _, n := range []int{1, 3, 5, 6, 8} {
+			for j := 0; j+n <= len(exact); j++ {
+				m := shrek.ContainsMatcher{Text: exact[j : j+n]}
+				if !m.MatchApprox(approx) {
+					t.Fatalf("approx rejected %q in %q (approx: %q)", m.Text, exact, approx)
+				}
+			}
+		}
+	}
+}
+
+func TestContainsMatcher_MatchApprox_Tail(t *testing.T) {
+	t.Parallel()
+
+	addr, err := shrek.GenerateOnionAddress(bytes.NewBufferString(seed))
+	if err != nil {
+		t.Fatalf("could not generate the prerequisite onion address: %v", err)
+	}
+	approx := make([]byte, shrek.EncodedPublicKeySize)
+	addr.HostNameApprox(approx)
+	accurate := approx[:shrek.EncodedPublicKeyApproxSize]
+
+	table := []struct {
+		Text  string
+		Match bool
+	}{
+		// The tail of this address is "q" then 4 inaccurate chars, so "q" is its 52nd char
+		// and the 52nd char of the exact address must be from "q" to "7". The 2 chars after
+		// that are checksum, and can be anything, so any 2 char text could be there.
+		{Text: "zz", Match: true},
+		{Text: "zzz", Match: true},
+		{Text: "zzyd", Match: true},
+		{Text: "zzzz", Match: false},
+		{Text: "aab", Match: false},
+		{Text: "xyzd", Match: false},
+	}
+
+	for _, tc := range table {
+		if bytes.Contains(accurate, []byte(tc.Text)) {
+			t.Fatalf("prerequisite address already contains %q: %s", tc.Text, approx)
+		}
+
+		m := shrek.ContainsMatcher{Text: []byte(tc.Text)}
+		if match := m.MatchApprox(approx); match != tc.Match {
+			t.Errorf("unexpected approx match for %q, got: %v, wanted: %v", tc.Text, match, tc.Match)
+		}
+	}
+}
+
+func TestStartEndMatcher_Difficulty(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		Start string
+		End   string
+		Want  shrek.Difficulty
+	}{
+		{Start: "", End: "", Want: shrek.Difficulty{}},
+		{Start: "food", End: "", Want: shrek.Difficulty{PublicKeyBits: 20}},
+		{Start: "", End: "d", Want: shrek.Difficulty{FixedBits: 5}},
+		{Start: "", End: "yd", Want: shrek.Difficulty{ChecksumBits: 2, FixedBits: 8, ExactOnlyBits: 2}},
+		{Start: "", End: "xid", Want: shrek.Difficulty{ChecksumBits: 7, FixedBits: 8, ExactOnlyBits: 7}},
+		{Start: "", End: "2ayd", Want: shrek.Difficulty{ChecksumBits: 12, FixedBits: 8, ExactOnlyBits: 12}},
+		{
+			Start: "ab",
+			End:   "bc2ayd",
+			Want:  shrek.Difficulty{PublicKeyBits: 16, ChecksumBits: 16, FixedBits: 8, ExactOnlyBits: 17},
+		},
+	}
+
+	for _, tc := range table {
+		tc := tc
+		name := fmt.Sprintf("%s:%s", tc.Start, tc.End)
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			m := shrek.StartEndMatcher{
+				Start: []byte(tc.Start),
+				End:   []byte(tc.End),
+			}
+
+			if got := m.Difficulty(); got != tc.Want {
+				t.Errorf("invalid difficulty: got %+v, wanted %+v", got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestSuggestEnds(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		End  string
+		Want []string
+	}{
+		{End: "", Want: nil},
+		{End: "z", Want: []string{"d"}},
+		{End: "xyz", Want: []string{"xyd", "xqd", "xid", "xad"}},
+		{End: "foo", Want: []string{"fqd", "fid", "fyd", "fad"}},
+		{End: "XID", Want: []string{"xid", "xad", "xqd", "xyd"}},
+		{End: "b00d", Want: []string{"boqd", "boid", "boyd", "boad"}},
+		{End: "1abd", Want: []string{"laad", "laid", "laqd", "layd"}},
+	}
+
+	for _, tc := range table {
+		tc := tc
+
+		t.Run(tc.End, func(t *testing.T) {
+			t.Parallel()
+
+			got := shrek.SuggestEnds([]byte(tc.End))
+			if fmt.Sprint(got) != fmt.Sprint(tc.Want) {
+				t.Errorf("invalid suggestions: got %q, wanted %q", got, tc.Want)
+			}
+
+			// Every suggestion must be a valid end part.
+			for _, end := range got {
+				m := shrek.StartEndMatcher{End: []byte(end)}
+				if err := m.Validate(); err != nil {
+					t.Errorf("suggestion %q is not valid: %v", end, err)
+				}
+			}
+		})
+	}
+}
+
 func permutations(t *testing.T, charset []rune) []string {
 	t.Helper()
 
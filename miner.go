@@ -5,65 +5,325 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/innix/shrek/internal/ed25519"
 )
 
+const (
+	defaultBatchSize        = 256
+	defaultProgressInterval = time.Second
+)
+
+// ErrLimitReached is returned by MineOnionHostNameWithOptions when the search stopped
+// because it hit the limit set with WithMaxAttempts or WithTimeLimit, rather than
+// because it failed.
+var ErrLimitReached = errors.New("shrek: search limit reached")
+
+// MineResult holds an address found by a miner, along with details about the search
+// that found it.
+type MineResult struct {
+	// Address is the onion address that was found.
+	Address *OnionAddress
+
+	// Matcher is the matcher that matched the address. If the miner was given an "any"
+	// MultiMatcher, this is the inner matcher that matched; otherwise it's the matcher
+	// the miner was given.
+	Matcher Matcher
+
+	// Attempts is the number of keys that were tried, including the matching one.
+	Attempts uint64
+
+	// Counter is the value of the key iterator's counter when the match was found.
+	Counter uint64
+
+	// Elapsed is how long the search took.
+	Elapsed time.Duration
+
+	// WorkerID identifies the worker that found the address. It's set with the
+	// WithWorkerID option.
+	WorkerID int
+}
+
+// MineStats is a snapshot of a running search, passed to the function given to the
+// WithStatsFunc option.
+type MineStats struct {
+	// WorkerID is the ID given to the WithWorkerID option.
+	WorkerID int
+
+	// Attempts is the number of keys that have been tried so far.
+	Attempts uint64
+
+	// Elapsed is how long the search has been running for.
+	Elapsed time.Duration
+}
+
+// KeysPerSecond returns the average number of keys tried per second.
+func (s MineStats) KeysPerSecond() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+
+	return float64(s.Attempts) / s.Elapsed.Seconds()
+}
+
+// MineOption configures a call to MineOnionHostNameWithOptions.
+type MineOption func(*mineConfig)
+
+type mineConfig struct {
+	rand             io.Reader
+	workerID         int
+	statsFn          func(MineStats)
+	progressInterval time.Duration
+	maxAttempts      uint64
+	timeLimit        time.Duration
+	batchSize        int
+	result           *MineResult
+
+	startSeed    []byte
+	startCounter uint64
+}
+
+// WithRand sets the source of randomness used to pick the starting key. If it's not
+// set, or set to nil, then crypto/rand is used.
+func WithRand(rand io.Reader) MineOption {
+	return func(c *mineConfig) {
+		c.rand = rand
+	}
+}
+
+// WithWorkerID sets an ID that is copied into the MineResult and MineStats, so callers
+// that run several miners concurrently can tell them apart.
+func WithWorkerID(id int) MineOption {
+	return func(c *mineConfig) {
+		c.workerID = id
+	}
+}
+
+// WithStatsFunc sets a function that is called with the progress of the search, once
+// every progress interval (see WithProgressInterval) and once more when the search
+// ends. It's called from the mining goroutine, so it should return quickly.
+func WithStatsFunc(fn func(MineStats)) MineOption {
+	return func(c *mineConfig) {
+		c.statsFn = fn
+	}
+}
+
+// WithProgressInterval sets how often the stats function is called. The default is 1
+// second. The interval is only checked between batches, so it's approximate.
+func WithProgressInterval(d time.Duration) MineOption {
+	return func(c *mineConfig) {
+		c.progressInterval = d
+	}
+}
+
+// WithMaxAttempts sets the maximum number of keys to try before giving up. Zero, the
+// default, means there is no limit. Reaching the limit makes the search return
+// ErrLimitReached.
+func WithMaxAttempts(n uint64) MineOption {
+	return func(c *mineConfig) {
+		c.maxAttempts = n
+	}
+}
+
+// WithTimeLimit sets the maximum amount of time to search for before giving up. Zero,
+// the default, means there is no limit. Unlike a context deadline, reaching the limit
+// makes the search return ErrLimitReached. The limit is only checked between batches,
+// so it's approximate.
+func WithTimeLimit(d time.Duration) MineOption {
+	return func(c *mineConfig) {
+		c.timeLimit = d
+	}
+}
+
+// WithBatchSize sets how many keys are tried between checks of the context, the time
+// limit, and the progress interval. Bigger batches have less overhead, but make the miner slower to
+// respond to the context being cancelled. The default is 256.
+func WithBatchSize(n int) MineOption {
+	return func(c *mineConfig) {
+		c.batchSize = n
+	}
+}
+
+// WithResult sets a MineResult to fill in with details about the search. It's filled
+// in even if the search fails, in which case its Address and Matcher are nil.
+func WithResult(res *MineResult) MineOption {
+	return func(c *mineConfig) {
+		c.result = res
+	}
+}
+
+// WithIteratorStart makes the miner start from the key derived from the 32 byte seed,
+// advanced by counter, instead of a random key. Combined with MineResult.Counter, it
+// can be used to resume or reproduce a search. The counter must be a multiple of 8.
+//
+// Anyone who knows the seed can derive every key the miner tries, so the seed must be
+// kept as secret as the keys themselves.
+func WithIteratorStart(seed []byte, counter uint64) MineOption {
+	return func(c *mineConfig) {
+		c.startSeed = seed
+		c.startCounter = counter
+	}
+}
+
 func MineOnionHostName(ctx context.Context, rand io.Reader, m Matcher) (*OnionAddress, error) {
+	return MineOnionHostNameWithOptions(ctx, m, WithRand(rand))
+}
+
+// MineOnionHostNameResult does the same thing as MineOnionHostName, but it returns a
+// MineResult that describes the search as well as the address that was found. The
+// options are the same as for MineOnionHostNameWithOptions, e.g. WithWorkerID when
+// running several searches at once; WithRand and WithResult are set by rand and the
+// returned result.
+func MineOnionHostNameResult(ctx context.Context, rand io.Reader, m Matcher, opts ...MineOption) (*MineResult, error) {
+	var res MineResult
+	opts = append(opts[:len(opts):len(opts)], WithRand(rand), WithResult(&res))
+	if _, err := MineOnionHostNameWithOptions(ctx, m, opts...); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// MineOnionHostNameWithOptions searches for an onion address that matches m, the same
+// as MineOnionHostName, but its behavior can be configured with options.
+func MineOnionHostNameWithOptions(ctx context.Context, m Matcher, opts ...MineOption) (*OnionAddress, error) {
+	cfg := mineConfig{
+		batchSize:        defaultBatchSize,
+		progressInterval: defaultProgressInterval,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.batchSize <= 0 {
+		cfg.batchSize = 1
+	}
+
+	var res MineResult
+	if cfg.result == nil {
+		cfg.result = &res
+	}
+
+	addr, err := mine(ctx, m, &cfg)
+	if cfg.statsFn != nil {
+		cfg.statsFn(MineStats{
+			WorkerID: cfg.workerID,
+			Attempts: cfg.result.Attempts,
+			Elapsed:  cfg.result.Elapsed,
+		})
+	}
+
+	return addr, err
+}
+
+func mine(ctx context.Context, m Matcher, cfg *mineConfig) (*OnionAddress, error) {
+	start := time.Now()
+	lastProgress := start
 	hostname := make([]byte, EncodedPublicKeySize)
 
-	it, err := ed25519.NewKeyIterator(rand)
+	res := cfg.result
+	*res = MineResult{WorkerID: cfg.workerID}
+	defer func() {
+		res.Elapsed = time.Since(start)
+	}()
+
+	var it *ed25519.KeyIterator
+	var err error
+	if cfg.startSeed != nil {
+		it, err = ed25519.NewKeyIteratorAt(cfg.startSeed, cfg.startCounter)
+	} else {
+		it, err = ed25519.NewKeyIterator(cfg.rand)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("shrek: could not create key iterator: %w", err)
 	}
+	defer it.Destroy()
 
-	for more := true; ctx.Err() == nil; more = it.Next() {
-		if !more {
-			return nil, errors.New("shrek: searched entire address space and no match was found")
-		}
+	for ctx.Err() == nil {
+		for i := 0; i < cfg.batchSize; i++ {
+			if cfg.maxAttempts > 0 && res.Attempts >= cfg.maxAttempts {
+				return nil, ErrLimitReached
+			}
+			res.Attempts++
 
-		addr := &OnionAddress{
-			PublicKey: it.PublicKey(),
+			addr := &OnionAddress{
+				PublicKey: it.PublicKey(),
 
-			// The private key is not needed to generate the hostname. So to avoid pointless
-			// computation, we wait until a match has been found first.
-			SecretKey: nil,
-		}
+				// The private key is not needed to generate the hostname. So to avoid pointless
+				// computation, we wait until a match has been found first.
+				SecretKey: nil,
+			}
 
-		// The approximate encoder only generates the first 51 bytes of the hostname accurately;
-		// the last 5 bytes are wrong. But it is much faster, so it is used first then the exact
-		// encoder is used if a match is found here.
-		addr.HostNameApprox(hostname)
+			// The approximate encoder only generates the first 51 bytes of the hostname accurately;
+			// the last 5 bytes are wrong. But it is much faster, so it is used first then the exact
+			// encoder is used if a match is found here.
+			addr.HostNameApprox(hostname)
 
-		// Check if approximate hostname matches.
-		if !m.MatchApprox(hostname) {
-			continue
-		}
+			// Check if approximate hostname matches, then generate full hostname so we can check
+			// for exact match. Generating the full address on every iteration is avoided because
+			// it's much slower than the approx.
+			if m.MatchApprox(hostname) {
+				addr.HostName(hostname)
 
-		// Generate full hostname, so we can check for exact match. Generating the full address
-		// on every iteration is avoided because it's much slower than the approx.
-		addr.HostName(hostname)
+				if m.Match(hostname) {
+					if err := completeAddress(it, addr); err != nil {
+						return nil, err
+					}
 
-		// Check if exact hostname matches.
-		if !m.Match(hostname) {
-			continue
-		}
+					res.Address = addr
+					res.Matcher = matchingMatcher(m, hostname)
+					res.Counter = it.Counter()
+					return addr, nil
+				}
+			}
 
-		// Compute private key after a match has been found.
-		sk, err := it.PrivateKey()
-		if err != nil {
-			return nil, fmt.Errorf("shrek: could not compute private key: %w", err)
+			if !it.Next() {
+				return nil, errors.New("shrek: searched entire address space and no match was found")
+			}
 		}
-		addr.SecretKey = sk
 
-		// Sanity check keys retrieved from iterator.
-		kp := &ed25519.KeyPair{PublicKey: addr.PublicKey, PrivateKey: addr.SecretKey}
-		if err := kp.Validate(); err != nil {
-			return nil, fmt.Errorf("shrek: key validation failed: %w", err)
+		if cfg.timeLimit > 0 && time.Since(start) >= cfg.timeLimit {
+			return nil, ErrLimitReached
 		}
 
-		return addr, nil
+		if cfg.statsFn != nil && time.Since(lastProgress) >= cfg.progressInterval {
+			lastProgress = time.Now()
+			cfg.statsFn(MineStats{
+				WorkerID: cfg.workerID,
+				Attempts: res.Attempts,
+				Elapsed:  lastProgress.Sub(start),
+			})
+		}
 	}
 
 	return nil, ctx.Err()
 }
+
+// completeAddress computes the private key of a matching address from the iterator.
+func completeAddress(it *ed25519.KeyIterator, addr *OnionAddress) error {
+	// Compute private key after a match has been found.
+	sk, err := it.PrivateKey()
+	if err != nil {
+		return fmt.Errorf("shrek: could not compute private key: %w", err)
+	}
+	addr.SecretKey = sk
+
+	// Sanity check keys retrieved from iterator.
+	kp := &ed25519.KeyPair{PublicKey: addr.PublicKey, PrivateKey: addr.SecretKey}
+	if err := kp.Validate(); err != nil {
+		return fmt.Errorf("shrek: key validation failed: %w", err)
+	}
+
+	return nil
+}
+
+// matchingMatcher returns the most specific matcher in m that matches the exact hostname.
+func matchingMatcher(m Matcher, exact []byte) Matcher {
+	if mm, ok := m.(MultiMatcher); ok && !mm.All {
+		if im := mm.MatchingInner(exact); im != nil {
+			return matchingMatcher(im, exact)
+		}
+	}
+
+	return m
+}
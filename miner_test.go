@@ -0,0 +1,130 @@
+package shrek_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/innix/shrek"
+)
+
+func TestMineOnionHostNameResult(t *testing.T) {
+	t.Parallel()
+
+	want := shrek.StartEndMatcher{Start: []byte("ab")}
+	m := shrek.MultiMatcher{
+		Inner: []shrek.Matcher{shrek.StartEndMatcher{Start: []byte("zzzzzzzzzz")}, want},
+	}
+
+	res, err := shrek.MineOnionHostNameResult(context.Background(), bytes.NewBufferString(seed), m, shrek.WithWorkerID(3))
+	if err != nil {
+		t.Fatalf("could not mine onion address: %v", err)
+	}
+
+	if hostname := res.Address.HostNameString(); !strings.HasPrefix(hostname, "ab") {
+		t.Errorf("mined address does not match, got: %q", hostname)
+	}
+	if sem, ok := res.Matcher.(shrek.StartEndMatcher); !ok || string(sem.Start) != string(want.Start) {
+		t.Errorf("unexpected matcher in result, got: %#v, wanted: %#v", res.Matcher, want)
+	}
+	if res.Attempts == 0 {
+		t.Errorf("expected non-zero attempts")
+	}
+	if res.WorkerID != 3 {
+		t.Errorf("unexpected worker id, got: %d, wanted: %d", res.WorkerID, 3)
+	}
+	if wanted := (res.Attempts - 1) * 8; res.Counter != wanted {
+		t.Errorf("unexpected counter, got: %d, wanted: %d", res.Counter, wanted)
+	}
+}
+
+func TestMineOnionHostNameWithOptions(t *testing.T) {
+	t.Parallel()
+
+	startSeed := []byte(seed[:32])
+	m := shrek.StartEndMatcher{Start: []byte("ab")}
+
+	var first shrek.MineResult
+	var stats []shrek.MineStats
+	addr, err := shrek.MineOnionHostNameWithOptions(context.Background(), m,
+		shrek.WithIteratorStart(startSeed, 0),
+		shrek.WithWorkerID(7),
+		shrek.WithBatchSize(1),
+		shrek.WithResult(&first),
+		shrek.WithStatsFunc(func(s shrek.MineStats) { stats = append(stats, s) }),
+	)
+	if err != nil {
+		t.Fatalf("could not mine onion address: %v", err)
+	}
+
+	if first.Address != addr {
+		t.Errorf("result address does not match returned address")
+	}
+	if first.WorkerID != 7 {
+		t.Errorf("unexpected worker ID, got: %d, wanted: %d", first.WorkerID, 7)
+	}
+	if l := len(stats); l == 0 || stats[l-1].Attempts != first.Attempts || stats[l-1].WorkerID != 7 {
+		t.Errorf("unexpected final stats, got: %+v, wanted attempts: %d", stats, first.Attempts)
+	}
+
+	// Resuming from the counter of the first match must find the same address again
+	// on the very first attempt.
+	var resumed shrek.MineResult
+	_, err = shrek.MineOnionHostNameWithOptions(context.Background(), m,
+		shrek.WithIteratorStart(startSeed, first.Counter),
+		shrek.WithResult(&resumed),
+	)
+	if err != nil {
+		t.Fatalf("could not resume mining: %v", err)
+	}
+
+	if got, wanted := resumed.Address.HostNameString(), first.Address.HostNameString(); got != wanted {
+		t.Errorf("resumed search found a different address, got: %q, wanted: %q", got, wanted)
+	}
+	if !bytes.Equal(resumed.Address.SecretKey, first.Address.SecretKey) {
+		t.Errorf("resumed search found a different secret key")
+	}
+	if resumed.Attempts != 1 {
+		t.Errorf("unexpected attempts after resuming, got: %d, wanted: %d", resumed.Attempts, 1)
+	}
+}
+
+func TestMineOnionHostNameWithOptions_MaxAttempts(t *testing.T) {
+	t.Parallel()
+
+	// Nothing can match this, so the miner must stop at the limit.
+	m := shrek.StartEndMatcher{Start: []byte("aaaaaaaaaaaaaaaaaaaa")}
+
+	var res shrek.MineResult
+	_, err := shrek.MineOnionHostNameWithOptions(context.Background(), m,
+		shrek.WithMaxAttempts(1000),
+		shrek.WithResult(&res),
+	)
+	if !errors.Is(err, shrek.ErrLimitReached) {
+		t.Fatalf("expected ErrLimitReached, got: %v", err)
+	}
+
+	if res.Attempts != 1000 {
+		t.Errorf("unexpected attempts, got: %d, wanted: %d", res.Attempts, 1000)
+	}
+	if res.Address != nil {
+		t.Errorf("expected nil address, got: %v", res.Address)
+	}
+}
+
+func TestMineOnionHostNameWithOptions_TimeLimit(t *testing.T) {
+	t.Parallel()
+
+	// Nothing can match this, so the miner must stop at the limit.
+	m := shrek.StartEndMatcher{Start: []byte("aaaaaaaaaaaaaaaaaaaa")}
+
+	_, err := shrek.MineOnionHostNameWithOptions(context.Background(), m,
+		shrek.WithTimeLimit(time.Millisecond*50),
+	)
+	if !errors.Is(err, shrek.ErrLimitReached) {
+		t.Fatalf("expected ErrLimitReached, got: %v", err)
+	}
+}
@@ -3,11 +3,17 @@ package shrek
 import (
 	"bytes"
 	"encoding/base32"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 
 	"github.com/innix/shrek/internal/ed25519"
 	"golang.org/x/crypto/sha3"
@@ -24,54 +30,118 @@ const (
 )
 
 const (
-	publicKeyFileName = "hs_ed25519_public_key"
-	secretKeyFileName = "hs_ed25519_secret_key"
-	hostNameFileName  = "hostname"
+	// PublicKeyFileName is the name of the file that SaveOnionAddress writes the public
+	// key to.
+	PublicKeyFileName = "hs_ed25519_public_key"
 
+	// SecretKeyFileName is the name of the file that SaveOnionAddress writes the secret
+	// key to.
+	SecretKeyFileName = "hs_ed25519_secret_key"
+
+	// HostNameFileName is the name of the file that SaveOnionAddress writes the hostname
+	// to.
+	HostNameFileName = "hostname"
+)
+
+const (
 	publicKeyFileHeader = "== ed25519v1-public: type0 ==\x00\x00\x00"
 	secretKeyFileHeader = "== ed25519v1-secret: type0 ==\x00\x00\x00"
 )
 
-var b32 = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+// base32Alphabet is the alphabet that onion addresses are encoded with.
+const base32Alphabet = "abcdefghijklmnopqrstuvwxyz234567"
+
+var b32 = base32.NewEncoding(base32Alphabet).WithPadding(base32.NoPadding)
 
 type OnionAddress struct {
 	PublicKey ed25519.PublicKey
 	SecretKey ed25519.PrivateKey
 }
 
+// AddressVersion is the version of onion address that this package works with.
+const AddressVersion = 3
+
 // HostName returns the .onion address representation of the public key stored in
 // the OnionAddress. The .onion TLD is not included.
 func (addr *OnionAddress) HostName(hostname []byte) {
-	const version = 3
-
 	if l := len(hostname); l != EncodedPublicKeySize {
 		panic(fmt.Sprintf("bad buffer length: %d", l))
 	}
+	if l := len(addr.PublicKey); l != ed25519.PublicKeySize {
+		panic(fmt.Sprintf("bad public key length: %d", l))
+	}
 
-	// checksum = sha3_sum256(".onion checksum" + public_key + version)
-	var checksumBuf bytes.Buffer
-	checksumBuf.Write([]byte(".onion checksum"))
-	checksumBuf.Write(addr.PublicKey)
-	checksumBuf.Write([]byte{version})
-	checksum := sha3.Sum256(checksumBuf.Bytes())
+	s := hostNameStatePool.Get().(*hostNameState)
+	defer hostNameStatePool.Put(s)
+
+	checksum := s.checksum(addr.PublicKey)
 
 	// onion_addr = base32_encode(public_key + checksum + version)
-	var onionAddrBuf bytes.Buffer
-	onionAddrBuf.Write(addr.PublicKey)
-	onionAddrBuf.Write(checksum[:2])
-	onionAddrBuf.Write([]byte{version})
+	copy(s.onionAddr[:], addr.PublicKey)
+	copy(s.onionAddr[ed25519.PublicKeySize:], checksum[:])
+	s.onionAddr[len(s.onionAddr)-1] = AddressVersion
+
+	b32.Encode(hostname, s.onionAddr[:])
+}
 
-	b32.Encode(hostname, onionAddrBuf.Bytes())
+// Checksum returns the 2 byte checksum that is encoded into the hostname, after the
+// public key.
+func (addr *OnionAddress) Checksum() [2]byte {
+	s := hostNameStatePool.Get().(*hostNameState)
+	defer hostNameStatePool.Put(s)
+
+	return s.checksum(addr.PublicKey)
 }
 
 // HostNameString returns the .onion address representation of the public key stored
 // in the OnionAddress as a string. Unlike HostName and HostNameApprox, this method
 // does include the .onion TLD in the returned hostname.
 func (addr *OnionAddress) HostNameString() string {
-	hostname := make([]byte, EncodedPublicKeySize)
-	addr.HostName(hostname)
+	var hostname [EncodedPublicKeySize + len(".onion")]byte
+	addr.HostName(hostname[:EncodedPublicKeySize])
+	copy(hostname[EncodedPublicKeySize:], ".onion")
+
+	return string(hostname[:])
+}
+
+// hostNameState holds the hash state and buffers needed to encode a hostname, so they
+// can be reused instead of allocated on every call to HostName.
+type hostNameState struct {
+	h      hash.Hash
+	prefix [len(checksumPrefix)]byte
+	digest [32]byte
+
+	// onionAddr = public_key + checksum + version
+	onionAddr [ed25519.PublicKeySize + 2 + 1]byte
+}
+
+const checksumPrefix = ".onion checksum"
+
+var hostNameStatePool = sync.Pool{
+	New: func() interface{} {
+		s := &hostNameState{h: sha3.New256()}
+		copy(s.prefix[:], checksumPrefix)
+		return s
+	},
+}
+
+func (s *hostNameState) checksum(pk []byte) [2]byte {
+	// checksum = sha3_sum256(".onion checksum" + public_key + version)
+	s.h.Reset()
+	s.h.Write(s.prefix[:])
+	s.h.Write(pk)
+	s.onionAddr[len(s.onionAddr)-1] = AddressVersion
+	s.h.Write(s.onionAddr[len(s.onionAddr)-1:])
+
+	// Sum allocates a copy of the state so it can carry on hashing. That's not needed
+	// here, so read the digest straight out of the sponge if the hash supports it.
+	if r, ok := s.h.(io.Reader); ok {
+		_, _ = r.Read(s.digest[:])
+	} else {
+		s.h.Sum(s.digest[:0])
+	}
 
-	return fmt.Sprintf("%s.onion", hostname)
+	return [2]byte{s.digest[0], s.digest[1]}
 }
 
 // HostNameApprox returns an approximate .onion address representation of the public
@@ -85,6 +155,54 @@ func (addr *OnionAddress) HostNameApprox(hostname []byte) {
 	b32.Encode(hostname, addr.PublicKey)
 }
 
+// ParseHostName parses an onion hostname, with or without the .onion TLD, and returns
+// an OnionAddress holding its public key. The returned OnionAddress has no secret key.
+// It returns an error if the hostname is not a valid v3 onion address, including if
+// its checksum is wrong.
+func ParseHostName(hostname string) (*OnionAddress, error) {
+	name := strings.TrimSuffix(strings.ToLower(hostname), ".onion")
+	if l := len(name); l != EncodedPublicKeySize {
+		return nil, fmt.Errorf("shrek: hostname has wrong length: %d", l)
+	}
+
+	data, err := b32.DecodeString(name)
+	if err != nil {
+		return nil, fmt.Errorf("shrek: hostname is not valid base32: %w", err)
+	}
+
+	// data = public_key + checksum + version
+	pk, checksum, version := data[:ed25519.PublicKeySize], data[ed25519.PublicKeySize:len(data)-1], data[len(data)-1]
+	if version != AddressVersion {
+		return nil, fmt.Errorf("shrek: hostname has unsupported version: %d", version)
+	}
+
+	addr := &OnionAddress{PublicKey: ed25519.PublicKey(pk)}
+	if want := addr.Checksum(); !bytes.Equal(checksum, want[:]) {
+		return nil, fmt.Errorf("shrek: hostname has wrong checksum: %x, expected %x", checksum, want)
+	}
+
+	return addr, nil
+}
+
+// ControlPortKey returns the secret key in the format used by the ADD_ONION command
+// of Tor's control port, i.e. "ED25519-V3:" followed by the base64 encoded key. It
+// returns an error if the OnionAddress has no secret key.
+func (addr *OnionAddress) ControlPortKey() (string, error) {
+	if l := len(addr.SecretKey); l != ed25519.PrivateKeySize {
+		return "", fmt.Errorf("shrek: secret key has wrong length: %d", l)
+	}
+
+	return "ED25519-V3:" + base64.StdEncoding.EncodeToString(addr.SecretKey), nil
+}
+
+// Destroy wipes the secret key from memory and removes it from the OnionAddress. The
+// public key, and so the hostname, are kept. Any copies of the secret key made
+// elsewhere, such as by SecretKeyFile, are not affected.
+func (addr *OnionAddress) Destroy() {
+	ed25519.Wipe(addr.SecretKey)
+	addr.SecretKey = nil
+}
+
 func GenerateOnionAddress(rand io.Reader) (*OnionAddress, error) {
 	kp, err := ed25519.GenerateKey(rand)
 	if err != nil {
@@ -97,6 +215,27 @@ func GenerateOnionAddress(rand io.Reader) (*OnionAddress, error) {
 	}, nil
 }
 
+// ErrAddressExists is returned by SaveOnionAddress, and by AddressStore
+// implementations, when the address has already been saved and overwriting it wasn't
+// allowed.
+var ErrAddressExists = errors.New("shrek: address already exists")
+
+// SaveOption configures a call to SaveOnionAddress.
+type SaveOption func(*saveConfig)
+
+type saveConfig struct {
+	overwrite bool
+	torrc     *TorrcConfig
+}
+
+// WithOverwrite sets whether SaveOnionAddress may replace an existing directory for the
+// same address. By default it returns ErrAddressExists instead.
+func WithOverwrite(allow bool) SaveOption {
+	return func(c *saveConfig) {
+		c.overwrite = allow
+	}
+}
+
 // SaveOnionAddress saves the hostname, public key, and secret key from the given
 // OnionAddress to the destination directory. It creates a sub-directory named after
 // the hostname in the destination directory, then it creates 3 files inside the
@@ -106,40 +245,149 @@ func GenerateOnionAddress(rand io.Reader) (*OnionAddress, error) {
 //   hs_ed25519_secret_key
 //   hostname
 //
-func SaveOnionAddress(dir string, addr *OnionAddress) error {
+// The files are written to a temporary directory first, synced to disk, then the
+// directory is renamed into place, so a crash never leaves a partially saved address
+// behind. If the sub-directory already exists, ErrAddressExists is returned unless
+// overwriting is allowed with the WithOverwrite option.
+func SaveOnionAddress(dir string, addr *OnionAddress, opts ...SaveOption) error {
 	const (
 		dirMode  = 0o700
 		fileMode = 0o600
 	)
 
+	var cfg saveConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	skData, err := addr.SecretKeyFile()
+	if err != nil {
+		return fmt.Errorf("shrek: could not save secret key to file: %w", err)
+	}
+	defer ed25519.Wipe(skData)
+
 	hostname := addr.HostNameString()
-	dir = filepath.Join(dir, hostname)
+	target := filepath.Join(dir, hostname)
 
 	if err := os.MkdirAll(dir, dirMode); err != nil {
 		return fmt.Errorf("shrek: could not create directories: %w", err)
 	}
 
-	pkFile := filepath.Join(dir, publicKeyFileName)
-	pkData := append([]byte(publicKeyFileHeader), addr.PublicKey...)
-	if err := os.WriteFile(pkFile, pkData, fileMode); err != nil {
-		return fmt.Errorf("shrek: could not save public key to file: %w", err)
+	exists := false
+	if _, err := os.Lstat(target); err == nil {
+		exists = true
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("shrek: could not check for existing address: %w", err)
+	}
+	if exists && !cfg.overwrite {
+		return fmt.Errorf("%w: %q", ErrAddressExists, target)
 	}
 
-	skFile := filepath.Join(dir, secretKeyFileName)
-	skData := append([]byte(secretKeyFileHeader), addr.SecretKey...)
-	if err := os.WriteFile(skFile, skData, fileMode); err != nil {
-		return fmt.Errorf("shrek: could not save secret key to file: %w", err)
+	// MkdirTemp creates the directory with mode 0700.
+	tmp, err := os.MkdirTemp(dir, "."+hostname+".tmp")
+	if err != nil {
+		return fmt.Errorf("shrek: could not create directories: %w", err)
 	}
+	defer os.RemoveAll(tmp)
 
-	hnFile := filepath.Join(dir, hostNameFileName)
-	hnData := []byte(hostname)
-	if err := os.WriteFile(hnFile, hnData, fileMode); err != nil {
-		return fmt.Errorf("shrek: could not save onion hostname to file: %w", err)
+	type file struct {
+		name string
+		data []byte
+		desc string
+	}
+	files := []file{
+		{name: PublicKeyFileName, data: addr.PublicKeyFile(), desc: "public key"},
+		{name: SecretKeyFileName, data: skData, desc: "secret key"},
+		{name: HostNameFileName, data: []byte(hostname), desc: "onion hostname"},
+	}
+	if cfg.torrc != nil {
+		snippet, err := addr.TorrcSnippet(*cfg.torrc)
+		if err != nil {
+			return fmt.Errorf("shrek: could not create torrc snippet: %w", err)
+		}
+		files = append(files, file{name: TorrcSnippetFileName, data: snippet, desc: "torrc snippet"})
+	}
+	for _, f := range files {
+		if err := writeFileSync(filepath.Join(tmp, f.name), f.data, fileMode); err != nil {
+			return fmt.Errorf("shrek: could not save %s to file: %w", f.desc, err)
+		}
+	}
+	if err := syncDir(tmp); err != nil {
+		return fmt.Errorf("shrek: could not sync directory: %w", err)
+	}
+
+	// A directory can't be renamed over one that isn't empty, so an existing address
+	// is moved out of the way first and only removed once the new one is in place.
+	var old string
+	if exists {
+		old = tmp + ".old"
+		if err := os.Rename(target, old); err != nil {
+			return fmt.Errorf("shrek: could not replace existing address: %w", err)
+		}
+	}
+
+	if err := os.Rename(tmp, target); err != nil {
+		if old != "" {
+			_ = os.Rename(old, target)
+		} else if _, serr := os.Lstat(target); serr == nil {
+			// Another process saved the same address in the meantime.
+			return fmt.Errorf("%w: %q", ErrAddressExists, target)
+		}
+		return fmt.Errorf("shrek: could not move address into place: %w", err)
+	}
+	if old != "" {
+		if err := os.RemoveAll(old); err != nil {
+			return fmt.Errorf("shrek: could not remove replaced address: %w", err)
+		}
+	}
+
+	if err := syncDir(dir); err != nil {
+		return fmt.Errorf("shrek: could not sync directory: %w", err)
 	}
 
 	return nil
 }
 
+// writeFileSync is like os.WriteFile, but it also flushes the file to disk before
+// closing it.
+func writeFileSync(name string, data []byte, perm fs.FileMode) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// syncDir flushes a directory's entries to disk, so that files created or renamed in
+// it survive a crash. Windows doesn't support syncing directories, so it's a no-op
+// there.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	if err := d.Sync(); err != nil {
+		d.Close()
+		return err
+	}
+
+	return d.Close()
+}
+
 // ReadOnionAddress reads the public key and secret key from the files in the given
 // directory, then it parses the keys from the files inside the directory and validates
 // that they are valid keys to use as an onion address.
@@ -165,31 +413,44 @@ func ReadOnionAddress(dir string) (*OnionAddress, error) {
 // ReadOnionAddressFS does the same thing as ReadOnionAddress. The only difference is
 // that it accepts an fs.FS to abstract away the underlying file system.
 func ReadOnionAddressFS(fsys fs.FS) (*OnionAddress, error) {
-	// Read public key from file and validate contents.
-	pkData, err := fs.ReadFile(fsys, publicKeyFileName)
+	// Read public key from file.
+	pkData, err := fs.ReadFile(fsys, PublicKeyFileName)
 	if err != nil {
 		return nil, fmt.Errorf("shrek: reading public key file: %w", err)
 	}
-	if l := len(pkData); l != len(publicKeyFileHeader)+ed25519.PublicKeySize {
-		return nil, fmt.Errorf("shrek: public key file has wrong length: %d", l)
-	}
 
-	// Read private key from file and validate contents.
-	skData, err := fs.ReadFile(fsys, secretKeyFileName)
+	// Read private key from file.
+	skData, err := fs.ReadFile(fsys, SecretKeyFileName)
 	if err != nil {
 		return nil, fmt.Errorf("shrek: reading secret key file: %w", err)
 	}
-	if l := len(skData); l != len(secretKeyFileHeader)+ed25519.PrivateKeySize {
-		return nil, fmt.Errorf("shrek: secret key file has wrong length: %d", l)
+	defer ed25519.Wipe(skData)
+
+	return parseKeyFiles(pkData, skData)
+}
+
+// parseKeyFiles parses the contents of the public and secret key files, and validates
+// that the keys match. The returned keys don't share memory with the file contents, so
+// the caller can wipe them.
+func parseKeyFiles(pkData, skData []byte) (*OnionAddress, error) {
+	pk, err := parseKeyFile(pkData, publicKeyFileHeader, ed25519.PublicKeySize, "public")
+	if err != nil {
+		return nil, err
+	}
+
+	sk, err := parseKeyFile(skData, secretKeyFileHeader, ed25519.PrivateKeySize, "secret")
+	if err != nil {
+		return nil, err
 	}
 
 	kp := &ed25519.KeyPair{
-		PublicKey:  ed25519.PublicKey(pkData[len(publicKeyFileHeader):]),
-		PrivateKey: ed25519.PrivateKey(skData[len(secretKeyFileHeader):]),
+		PublicKey:  append(ed25519.PublicKey(nil), pk...),
+		PrivateKey: append(ed25519.PrivateKey(nil), sk...),
 	}
 
 	// Validate keys match.
 	if err := kp.Validate(); err != nil {
+		ed25519.Wipe(kp.PrivateKey)
 		return nil, fmt.Errorf("shrek: keys in directory do not match: %w", err)
 	}
 
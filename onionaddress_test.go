@@ -2,8 +2,15 @@ package shrek_test
 
 import (
 	"bytes"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/innix/shrek"
 )
@@ -141,6 +148,21 @@ func TestGenerateOnionAddress(t *testing.T) {
 	}
 }
 
+func TestOnionAddress_HostName_Allocs(t *testing.T) {
+	addr, err := shrek.GenerateOnionAddress(bytes.NewBufferString(seed))
+	if err != nil {
+		t.Fatalf("could not generate the prerequisite onion address: %v", err)
+	}
+	hostname := make([]byte, shrek.EncodedPublicKeySize)
+
+	if n := testing.AllocsPerRun(100, func() { addr.HostName(hostname) }); n != 0 {
+		t.Errorf("expected HostName to not allocate, got: %v allocs per run", n)
+	}
+	if got := string(hostname); got != seedHostname {
+		t.Errorf("unexpected hostname, got: %q, wanted: %q", got, seedHostname)
+	}
+}
+
 func BenchmarkOnionAddress_HostName(b *testing.B) {
 	addr, err := shrek.GenerateOnionAddress(bytes.NewBufferString(seed))
 	if err != nil {
@@ -148,6 +170,7 @@ func BenchmarkOnionAddress_HostName(b *testing.B) {
 	}
 	hostname := make([]byte, shrek.EncodedPublicKeySize)
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		addr.HostName(hostname)
@@ -175,3 +198,191 @@ func BenchmarkGenerateOnionAddress(b *testing.B) {
 		}
 	}
 }
+
+func TestSaveOnionAddress(t *testing.T) {
+	t.Parallel()
+
+	addr, err := shrek.GenerateOnionAddress(bytes.NewBufferString(seed))
+	if err != nil {
+		t.Fatalf("could not generate the prerequisite onion address: %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := shrek.SaveOnionAddress(dir, addr); err != nil {
+		t.Fatalf("could not save address: %v", err)
+	}
+
+	if err := shrek.SaveOnionAddress(dir, addr); !errors.Is(err, shrek.ErrAddressExists) {
+		t.Errorf("expected ErrAddressExists saving address twice, got: %v", err)
+	}
+	if err := shrek.SaveOnionAddress(dir, addr, shrek.WithOverwrite(true)); err != nil {
+		t.Errorf("could not overwrite address: %v", err)
+	}
+
+	// Only the address directory should be left, no temporary files.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("could not read save dir: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != seedHostname+".onion" {
+		t.Errorf("unexpected entries in save dir: %v", entries)
+	}
+
+	got, err := shrek.ReadOnionAddress(filepath.Join(dir, seedHostname+".onion"))
+	if err != nil {
+		t.Fatalf("could not read saved address: %v", err)
+	}
+	assertSameAddress(t, got, addr)
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+
+	checkMode := func(name string, want os.FileMode) {
+		fi, err := os.Stat(filepath.Join(dir, seedHostname+".onion", name))
+		if err != nil {
+			t.Fatalf("could not stat %q: %v", name, err)
+		}
+		if got := fi.Mode().Perm(); got != want {
+			t.Errorf("unexpected mode of %q, got: %v, wanted: %v", name, got, want)
+		}
+	}
+	checkMode("", 0o700)
+	checkMode(shrek.PublicKeyFileName, 0o600)
+	checkMode(shrek.SecretKeyFileName, 0o600)
+	checkMode(shrek.HostNameFileName, 0o600)
+}
+
+func TestOnionAddress_Destroy(t *testing.T) {
+	t.Parallel()
+
+	addr, err := shrek.GenerateOnionAddress(bytes.NewBufferString(seed))
+	if err != nil {
+		t.Fatalf("could not generate the prerequisite onion address: %v", err)
+	}
+
+	sk := addr.SecretKey
+	addr.Destroy()
+
+	if addr.SecretKey != nil {
+		t.Errorf("expected secret key to be removed, got: %v", addr.SecretKey)
+	}
+	if !bytes.Equal(sk, make([]byte, len(sk))) {
+		t.Errorf("expected secret key memory to be wiped, got: %v", sk)
+	}
+	if got := addr.HostNameString(); got != seedHostname+".onion" {
+		t.Errorf("unexpected hostname after destroy, got: %q, wanted: %q", got, seedHostname+".onion")
+	}
+	if _, err := addr.SecretKeyFile(); !errors.Is(err, shrek.ErrNoSecretKey) {
+		t.Errorf("expected ErrNoSecretKey after destroy, got: %v", err)
+	}
+}
+
+func TestReadOnionAddressFS(t *testing.T) {
+	t.Parallel()
+
+	const (
+		pkHeader = "== ed25519v1-public: type0 ==\x00\x00\x00"
+		skHeader = "== ed25519v1-secret: type0 ==\x00\x00\x00"
+	)
+
+	badPublicKey := append([]byte{}, seedPublicKey...)
+	badPublicKey[0]++
+
+	table := []struct {
+		Name  string
+		PK    []byte
+		SK    []byte
+		Valid bool
+	}{
+		{Name: "valid", PK: append([]byte(pkHeader), seedPublicKey...), SK: append([]byte(skHeader), seedSecretKey...), Valid: true},
+		{Name: "bad pk header", PK: append([]byte(skHeader), seedPublicKey...), SK: append([]byte(skHeader), seedSecretKey...)},
+		{Name: "bad sk header", PK: append([]byte(pkHeader), seedPublicKey...), SK: append([]byte(pkHeader), seedSecretKey...)},
+		{Name: "short pk", PK: append([]byte(pkHeader), seedPublicKey[1:]...), SK: append([]byte(skHeader), seedSecretKey...)},
+		{Name: "short sk", PK: append([]byte(pkHeader), seedPublicKey...), SK: append([]byte(skHeader), seedSecretKey[1:]...)},
+		{Name: "mismatched", PK: append([]byte(pkHeader), badPublicKey...), SK: append([]byte(skHeader), seedSecretKey...)},
+	}
+
+	for _, tc := range table {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			fsys := fstest.MapFS{
+				shrek.PublicKeyFileName: &fstest.MapFile{Data: tc.PK},
+				shrek.SecretKeyFileName: &fstest.MapFile{Data: tc.SK},
+			}
+
+			addr, err := shrek.ReadOnionAddressFS(fsys)
+			if err != nil && tc.Valid {
+				t.Fatalf("unexpected error: %v", err)
+			} else if err == nil && !tc.Valid {
+				t.Fatalf("expected error, got nil")
+			}
+
+			if tc.Valid && addr.HostNameString() != seedHostname+".onion" {
+				t.Errorf("unexpected hostname, got: %q, wanted: %q", addr.HostNameString(), seedHostname+".onion")
+			}
+		})
+	}
+}
+
+func TestParseHostName(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		HostName string
+		Valid    bool
+	}{
+		{HostName: seedHostname, Valid: true},
+		{HostName: seedHostname + ".onion", Valid: true},
+		{HostName: strings.ToUpper(seedHostname) + ".onion", Valid: true},
+		{HostName: seedHostname[:len(seedHostname)-1], Valid: false},
+		{HostName: seedHostname[:len(seedHostname)-2] + "ad", Valid: false},
+		{HostName: seedHostname[:len(seedHostname)-1] + "e", Valid: false},
+		{HostName: "1" + seedHostname[1:], Valid: false},
+	}
+
+	for _, tc := range table {
+		tc := tc
+
+		t.Run(tc.HostName, func(t *testing.T) {
+			t.Parallel()
+
+			addr, err := shrek.ParseHostName(tc.HostName)
+			if err != nil && tc.Valid {
+				t.Fatalf("unexpected error: %v", err)
+			} else if err == nil && !tc.Valid {
+				t.Fatalf("expected error, got nil")
+			}
+
+			if tc.Valid && !bytes.Equal(addr.PublicKey, seedPublicKey) {
+				t.Errorf("unexpected public key, got: %v, wanted: %v", addr.PublicKey, seedPublicKey)
+			}
+		})
+	}
+}
+
+func TestOnionAddress_ControlPortKey(t *testing.T) {
+	t.Parallel()
+
+	addr, err := shrek.GenerateOnionAddress(bytes.NewBufferString(seed))
+	if err != nil {
+		t.Fatalf("could not generate the prerequisite onion address: %v", err)
+	}
+
+	key, err := addr.ControlPortKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wanted := "ED25519-V3:" + base64.StdEncoding.EncodeToString(seedSecretKey)
+	if key != wanted {
+		t.Errorf("unexpected control port key, got: %q, wanted: %q", key, wanted)
+	}
+
+	if _, err := (&shrek.OnionAddress{PublicKey: addr.PublicKey}).ControlPortKey(); err == nil {
+		t.Errorf("expected error for address without secret key, got nil")
+	}
+}
@@ -0,0 +1,194 @@
+package shrek
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PatternPart identifies which part of a pattern a PatternError relates to.
+type PatternPart string
+
+const (
+	// PatternPartNone means the error relates to the pattern as a whole.
+	PatternPartNone = PatternPart("")
+
+	// PatternPartStart is the start part of a "start:end" pattern.
+	PatternPartStart = PatternPart("start")
+
+	// PatternPartEnd is the end part of a "start:end" pattern.
+	PatternPartEnd = PatternPart("end")
+
+	// PatternPartLength is the length part of a structural pattern, e.g. the "6" in
+	// "repeat:6".
+	PatternPartLength = PatternPart("length")
+)
+
+// PatternError is returned when a pattern, or a matcher built from a pattern, is not
+// valid. It carries enough detail for a frontend to point at the offending part and
+// char, and its message is plain text without any formatting.
+type PatternError struct {
+	// Pattern is the pattern text that failed to parse. It's empty if the error came
+	// from validating a matcher directly.
+	Pattern string
+
+	// Part is the part of the pattern that is not valid.
+	Part PatternPart
+
+	// Index is the byte position of the offending char within Part, or -1 if the error
+	// is not caused by a specific char.
+	Index int
+
+	// Char is the offending char. It's only meaningful if Index is not -1.
+	Char rune
+
+	// Reason is a human readable description of why the pattern is not valid.
+	Reason string
+
+	// Suggestions contains valid replacements for Part, closest first. It's empty if
+	// there are no suggestions.
+	Suggestions []string
+}
+
+func (e *PatternError) Error() string {
+	if e.Pattern == "" {
+		return fmt.Sprintf("shrek: %s", e.Reason)
+	}
+
+	return fmt.Sprintf("shrek: pattern %q is not valid: %s", e.Pattern, e.Reason)
+}
+
+// ParsePattern parses a single pattern into a Matcher. The supported syntax is:
+//
+//   start[:end]    address starts with start and ends with end
+//   repeat:N       address starts with N identical chars
+//   palindrome:N   address starts with an N char palindrome
+//   alpha:N        first N chars of address are letters only
+//
+// If the pattern is not valid, the returned error is a *PatternError.
+func ParsePattern(pattern string) (Matcher, error) {
+	m, err := parsePattern(pattern)
+	if err != nil {
+		if pe, ok := err.(*PatternError); ok {
+			pe.Pattern = pattern
+		}
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// ParsePatterns parses each of the patterns using ParsePattern and combines them into
+// a MultiMatcher that matches if any of the patterns match.
+func ParsePatterns(patterns []string) (MultiMatcher, error) {
+	var mm MultiMatcher
+
+	for _, pattern := range patterns {
+		m, err := ParsePattern(pattern)
+		if err != nil {
+			return MultiMatcher{}, err
+		}
+		mm.Inner = append(mm.Inner, m)
+	}
+
+	return mm, nil
+}
+
+func parsePattern(pattern string) (Matcher, error) {
+	parts := strings.Split(pattern, ":")
+
+	// Structural shorthands, e.g. "repeat:6". The length part is always a decimal number,
+	// which can never be a valid end part, so they don't clash with start:end patterns.
+	if len(parts) == 2 {
+		m, err := structuralMatcher(parts[0], parts[1])
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			if err := m.Validate(); err != nil {
+				return nil, err
+			}
+			return m, nil
+		}
+	}
+
+	var m StartEndMatcher
+	switch len(parts) {
+	case 1:
+		m = StartEndMatcher{Start: []byte(parts[0])}
+	case 2:
+		m = StartEndMatcher{Start: []byte(parts[0]), End: []byte(parts[1])}
+	default:
+		return nil, &PatternError{
+			Part:   PatternPartNone,
+			Index:  -1,
+			Reason: fmt.Sprintf("expected at most 1 %q separator, found %d", ":", len(parts)-1),
+		}
+	}
+
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// structuralMatcher returns the matcher for a structural shorthand, or nil if name isn't
+// one. A length made of digits and signs can't be a valid end part, so if it isn't a
+// plain decimal number, an error is returned instead of treating it as one.
+func structuralMatcher(name, length string) (validatingMatcher, error) {
+	switch name {
+	case "repeat", "palindrome", "alpha":
+	default:
+		return nil, nil
+	}
+
+	if length == "" || strings.Trim(length, "0123456789+-") != "" {
+		return nil, nil
+	}
+	if !isDecimal(length) {
+		return nil, &PatternError{
+			Part:   PatternPartLength,
+			Index:  -1,
+			Reason: fmt.Sprintf("%s length must be a number without a sign or leading zeros, not %q", name, length),
+		}
+	}
+
+	n, err := strconv.Atoi(length)
+	if err != nil {
+		return nil, &PatternError{
+			Part:   PatternPartLength,
+			Index:  -1,
+			Reason: fmt.Sprintf("%s length is not valid: %q", name, length),
+		}
+	}
+
+	switch name {
+	case "repeat":
+		return RepeatMatcher{Length: n}, nil
+	case "palindrome":
+		return PalindromeMatcher{Length: n}, nil
+	default:
+		return AlphaMatcher{Length: n}, nil
+	}
+}
+
+// isDecimal reports whether s is a non-empty string of decimal digits with no leading
+// zeros, other than "0" itself.
+func isDecimal(s string) bool {
+	if s == "" || (len(s) > 1 && s[0] == '0') {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+type validatingMatcher interface {
+	Matcher
+	Validate() error
+}
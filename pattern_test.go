@@ -0,0 +1,119 @@
+package shrek_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/innix/shrek"
+)
+
+func TestParsePattern(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		Pattern string
+		Want    shrek.Matcher
+	}{
+		{Pattern: "food", Want: shrek.StartEndMatcher{Start: []byte("food")}},
+		{Pattern: "food:xid", Want: shrek.StartEndMatcher{Start: []byte("food"), End: []byte("xid")}},
+		{Pattern: ":2ayd", Want: shrek.StartEndMatcher{Start: []byte(""), End: []byte("2ayd")}},
+		{Pattern: "repeat:6", Want: shrek.RepeatMatcher{Length: 6}},
+		{Pattern: "palindrome:7", Want: shrek.PalindromeMatcher{Length: 7}},
+		{Pattern: "alpha:10", Want: shrek.AlphaMatcher{Length: 10}},
+		{Pattern: "repeat:ad", Want: shrek.StartEndMatcher{Start: []byte("repeat"), End: []byte("ad")}},
+		{Pattern: "repeat:", Want: shrek.StartEndMatcher{Start: []byte("repeat"), End: []byte("")}},
+	}
+
+	for _, tc := range table {
+		tc := tc
+
+		t.Run(tc.Pattern, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := shrek.ParsePattern(tc.Pattern)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tc.Want) {
+				t.Errorf("unexpected matcher, got: %#v, wanted: %#v", got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestParsePattern_Error(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		Pattern string
+		Part    shrek.PatternPart
+		Index   int
+		Char    rune
+	}{
+		{Pattern: "a:b:c", Part: shrek.PatternPartNone, Index: -1},
+		{Pattern: "fo0d", Part: shrek.PatternPartStart, Index: 2, Char: '0'},
+		{Pattern: "Food", Part: shrek.PatternPartStart, Index: 0, Char: 'F'},
+		{Pattern: "food:x1d", Part: shrek.PatternPartEnd, Index: 1, Char: '1'},
+		{Pattern: "food:xyz", Part: shrek.PatternPartEnd, Index: 2, Char: 'z'},
+		{Pattern: "food:xbd", Part: shrek.PatternPartEnd, Index: 1, Char: 'b'},
+		{Pattern: "café", Part: shrek.PatternPartStart, Index: 3, Char: 'é'},
+		{Pattern: "food:xéd", Part: shrek.PatternPartEnd, Index: 1, Char: 'é'},
+		{Pattern: "repeat:0", Part: shrek.PatternPartLength, Index: -1},
+		{Pattern: "alpha:57", Part: shrek.PatternPartLength, Index: -1},
+		{Pattern: "repeat:+6", Part: shrek.PatternPartLength, Index: -1},
+		{Pattern: "repeat:-6", Part: shrek.PatternPartLength, Index: -1},
+		{Pattern: "palindrome:07", Part: shrek.PatternPartLength, Index: -1},
+		{Pattern: "alpha:1+0", Part: shrek.PatternPartLength, Index: -1},
+		{Pattern: "repeat:99999999999999999999", Part: shrek.PatternPartLength, Index: -1},
+	}
+
+	for _, tc := range table {
+		tc := tc
+
+		t.Run(tc.Pattern, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := shrek.ParsePattern(tc.Pattern)
+
+			var pe *shrek.PatternError
+			if !errors.As(err, &pe) {
+				t.Fatalf("expected *PatternError, got: %v", err)
+			}
+
+			if pe.Pattern != tc.Pattern {
+				t.Errorf("unexpected pattern, got: %q, wanted: %q", pe.Pattern, tc.Pattern)
+			}
+			if pe.Part != tc.Part {
+				t.Errorf("unexpected part, got: %q, wanted: %q", pe.Part, tc.Part)
+			}
+			if pe.Index != tc.Index {
+				t.Errorf("unexpected index, got: %d, wanted: %d", pe.Index, tc.Index)
+			}
+			if pe.Index != -1 && pe.Char != tc.Char {
+				t.Errorf("unexpected char, got: %q, wanted: %q", pe.Char, tc.Char)
+			}
+		})
+	}
+}
+
+func TestParsePatterns(t *testing.T) {
+	t.Parallel()
+
+	mm, err := shrek.ParsePatterns([]string{"food:xid", "barn", "repeat:5"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if mm.All {
+		t.Errorf("expected any-of matcher, got all-of matcher")
+	}
+	if l := len(mm.Inner); l != 3 {
+		t.Errorf("unexpected number of inner matchers, got: %d, wanted: %d", l, 3)
+	}
+
+	if _, err := shrek.ParsePatterns([]string{"food", "b8rn"}); err == nil {
+		t.Errorf("expected error for invalid pattern, got nil")
+	}
+}
@@ -0,0 +1,33 @@
+package shrek
+
+import (
+	"fmt"
+
+	"github.com/innix/shrek/internal/ed25519"
+)
+
+// SignatureSize is the size, in bytes, of the signatures made by OnionAddress.Sign.
+const SignatureSize = ed25519.SignatureSize
+
+// Sign signs the message with the address's secret key. The signature is a standard
+// Ed25519 signature, which anyone can check against the public key encoded in the
+// hostname, e.g. with Verify. It returns ErrNoSecretKey if the address has no secret
+// key.
+func (addr *OnionAddress) Sign(msg []byte) ([]byte, error) {
+	if len(addr.SecretKey) == 0 {
+		return nil, ErrNoSecretKey
+	}
+
+	sig, err := ed25519.Sign(addr.SecretKey, addr.PublicKey, msg)
+	if err != nil {
+		return nil, fmt.Errorf("shrek: could not sign message: %w", err)
+	}
+
+	return sig, nil
+}
+
+// Verify reports whether sig is a valid signature of the message by the public key, as
+// made by OnionAddress.Sign.
+func Verify(pub, msg, sig []byte) bool {
+	return ed25519.Verify(pub, msg, sig)
+}
@@ -0,0 +1,74 @@
+package shrek_test
+
+import (
+	"bytes"
+	"context"
+	stded25519 "crypto/ed25519"
+	"errors"
+	"testing"
+
+	"github.com/innix/shrek"
+)
+
+func TestOnionAddress_Sign(t *testing.T) {
+	t.Parallel()
+
+	addr, err := shrek.GenerateOnionAddress(bytes.NewBufferString(seed))
+	if err != nil {
+		t.Fatalf("could not generate the prerequisite onion address: %v", err)
+	}
+
+	msg := []byte("prove you own this address")
+	sig, err := addr.Sign(msg)
+	if err != nil {
+		t.Fatalf("could not sign message: %v", err)
+	}
+
+	// The address was derived from a seed, so the signature must match the one made by
+	// the standard library from the same seed.
+	stdKey := stded25519.NewKeyFromSeed([]byte(seed)[:stded25519.SeedSize])
+	if want := stded25519.Sign(stdKey, msg); !bytes.Equal(sig, want) {
+		t.Errorf("unexpected signature, got: %x, wanted: %x", sig, want)
+	}
+
+	if !shrek.Verify(addr.PublicKey, msg, sig) {
+		t.Errorf("expected signature to verify")
+	}
+	if shrek.Verify(addr.PublicKey, []byte("something else"), sig) {
+		t.Errorf("expected signature of another message not to verify")
+	}
+	if shrek.Verify(addr.PublicKey[1:], msg, sig) || shrek.Verify(addr.PublicKey, msg, sig[1:]) {
+		t.Errorf("expected truncated public key or signature not to verify")
+	}
+
+	// Signing wipes its working copies of the secret, but must leave the key itself alone.
+	if again, err := addr.Sign(msg); err != nil || !bytes.Equal(again, sig) {
+		t.Errorf("expected signing again to give the same signature, got: %x, %v", again, err)
+	}
+
+	if _, err := (&shrek.OnionAddress{PublicKey: addr.PublicKey}).Sign(msg); !errors.Is(err, shrek.ErrNoSecretKey) {
+		t.Errorf("expected ErrNoSecretKey, got: %v", err)
+	}
+}
+
+func TestOnionAddress_Sign_MinedAddress(t *testing.T) {
+	t.Parallel()
+
+	// Mined keys are never derived from a seed, so they can only be signed with using
+	// the expanded key.
+	for i := 0; i < 8; i++ {
+		addr, err := shrek.MineOnionHostName(context.Background(), nil, shrek.StartEndMatcher{Start: []byte("a")})
+		if err != nil {
+			t.Fatalf("could not mine the prerequisite onion address: %v", err)
+		}
+
+		msg := []byte(addr.HostNameString())
+		sig, err := addr.Sign(msg)
+		if err != nil {
+			t.Fatalf("could not sign message: %v", err)
+		}
+		if !shrek.Verify(addr.PublicKey, msg, sig) {
+			t.Errorf("expected signature by %s to verify", addr.HostNameString())
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package shrek
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// SpecKind is the kind of matcher described by a MatcherSpec.
+type SpecKind string
+
+const (
+	// SpecKindPattern is a pattern in the syntax accepted by ParsePattern.
+	SpecKindPattern = SpecKind("pattern")
+
+	// SpecKindStartEnd builds a StartEndMatcher.
+	SpecKindStartEnd = SpecKind("start_end")
+
+	// SpecKindAny builds a MultiMatcher that matches if any of the inner specs match.
+	SpecKindAny = SpecKind("any")
+
+	// SpecKindAll builds a MultiMatcher that matches if all of the inner specs match.
+	SpecKindAll = SpecKind("all")
+
+	// SpecKindContains builds a ContainsMatcher.
+	SpecKindContains = SpecKind("contains")
+
+	// SpecKindRegex builds a RegexMatcher.
+	SpecKindRegex = SpecKind("regex")
+
+	// SpecKindRepeat builds a RepeatMatcher.
+	SpecKindRepeat = SpecKind("repeat")
+
+	// SpecKindPalindrome builds a PalindromeMatcher.
+	SpecKindPalindrome = SpecKind("palindrome")
+
+	// SpecKindAlpha builds an AlphaMatcher.
+	SpecKindAlpha = SpecKind("alpha")
+)
+
+// MatcherSpec is a declarative, serializable description of a Matcher. Unlike Matcher
+// values, specs can be stored and sent around as JSON (or YAML), then turned into a
+// Matcher with Build.
+//
+// A spec can be written in full as an object:
+//
+//   {"kind": "all", "inner": [{"kind": "start_end", "start": "food"}, {"kind": "contains", "text": "ogre"}]}
+//
+// Or, for a single pattern, as a plain string using the ParsePattern syntax:
+//
+//   "food:xid"
+//
+// Any spec can have exclusions. An address that matches any of the Exclude specs is
+// rejected, even if it matches the spec itself.
+type MatcherSpec struct {
+	Kind SpecKind `json:"kind" yaml:"kind"`
+
+	// Pattern is used by SpecKindPattern.
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+
+	// Start and End are used by SpecKindStartEnd.
+	Start string `json:"start,omitempty" yaml:"start,omitempty"`
+	End   string `json:"end,omitempty" yaml:"end,omitempty"`
+
+	// Text is used by SpecKindContains.
+	Text string `json:"text,omitempty" yaml:"text,omitempty"`
+
+	// Regex is used by SpecKindRegex.
+	Regex string `json:"regex,omitempty" yaml:"regex,omitempty"`
+
+	// Length is used by SpecKindRepeat, SpecKindPalindrome, and SpecKindAlpha.
+	Length int `json:"length,omitempty" yaml:"length,omitempty"`
+
+	// Inner is used by SpecKindAny and SpecKindAll.
+	Inner []MatcherSpec `json:"inner,omitempty" yaml:"inner,omitempty"`
+
+	// Exclude can be used by any kind.
+	Exclude []MatcherSpec `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+}
+
+// specFields has the same fields as MatcherSpec but none of its methods, so it can be
+// passed to the encoders without recursing back into them.
+type specFields MatcherSpec
+
+// Build validates the spec and returns the Matcher it describes.
+func (s MatcherSpec) Build() (Matcher, error) {
+	m, err := s.build()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(s.Exclude) == 0 {
+		return m, nil
+	}
+
+	em := ExcludeMatcher{Inner: m}
+	for _, es := range s.Exclude {
+		m, err := es.Build()
+		if err != nil {
+			return nil, err
+		}
+		em.Exclude = append(em.Exclude, m)
+	}
+
+	return em, nil
+}
+
+func (s MatcherSpec) build() (Matcher, error) {
+	var m validatingMatcher
+
+	switch s.Kind {
+	case SpecKindPattern:
+		// An empty pattern matches every address, which is never what a spec means.
+		if s.Pattern == "" {
+			return nil, errors.New("shrek: pattern spec is empty")
+		}
+		return ParsePattern(s.Pattern)
+	case SpecKindStartEnd:
+		m = StartEndMatcher{Start: []byte(s.Start), End: []byte(s.End)}
+	case SpecKindContains:
+		m = ContainsMatcher{Text: []byte(s.Text)}
+	case SpecKindRepeat:
+		m = RepeatMatcher{Length: s.Length}
+	case SpecKindPalindrome:
+		m = PalindromeMatcher{Length: s.Length}
+	case SpecKindAlpha:
+		m = AlphaMatcher{Length: s.Length}
+	case SpecKindRegex:
+		re, err := regexp.Compile(s.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("shrek: regex spec is not valid: %w", err)
+		}
+		return RegexMatcher{Regexp: re}, nil
+	case SpecKindAny, SpecKindAll:
+		if len(s.Inner) == 0 {
+			return nil, fmt.Errorf("shrek: %s spec must have at least 1 inner spec", s.Kind)
+		}
+
+		mm := MultiMatcher{All: s.Kind == SpecKindAll}
+		for _, is := range s.Inner {
+			im, err := is.Build()
+			if err != nil {
+				return nil, err
+			}
+			mm.Inner = append(mm.Inner, im)
+		}
+		return mm, nil
+	case "":
+		return nil, errors.New("shrek: spec kind is missing")
+	default:
+		return nil, fmt.Errorf("shrek: unknown spec kind: %q", s.Kind)
+	}
+
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// isShorthand reports whether the spec can be encoded as a plain pattern string.
+func (s MatcherSpec) isShorthand() bool {
+	return s.Kind == SpecKindPattern && len(s.Exclude) == 0
+}
+
+func (s MatcherSpec) MarshalJSON() ([]byte, error) {
+	if s.isShorthand() {
+		return json.Marshal(s.Pattern)
+	}
+
+	return json.Marshal(specFields(s))
+}
+
+func (s *MatcherSpec) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return errors.New("shrek: spec is null")
+	}
+
+	var pattern string
+	if err := json.Unmarshal(data, &pattern); err == nil {
+		*s = MatcherSpec{Kind: SpecKindPattern, Pattern: pattern}
+		return nil
+	}
+
+	var fields specFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("shrek: spec must be a pattern string or an object: %w", err)
+	}
+	*s = MatcherSpec(fields)
+
+	return nil
+}
+
+// MarshalYAML implements the marshaler interface used by the popular YAML packages,
+// without needing to depend on any of them.
+func (s MatcherSpec) MarshalYAML() (interface{}, error) {
+	if s.isShorthand() {
+		return s.Pattern, nil
+	}
+
+	return specFields(s), nil
+}
+
+// UnmarshalYAML implements the unmarshaler interface used by the popular YAML packages,
+// without needing to depend on any of them.
+func (s *MatcherSpec) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var pattern string
+	if err := unmarshal(&pattern); err == nil {
+		*s = MatcherSpec{Kind: SpecKindPattern, Pattern: pattern}
+		return nil
+	}
+
+	var fields specFields
+	if err := unmarshal(&fields); err != nil {
+		return fmt.Errorf("shrek: spec must be a pattern string or a mapping: %w", err)
+	}
+	*s = MatcherSpec(fields)
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package shrek_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/innix/shrek"
+)
+
+func TestMatcherSpec_JSON(t *testing.T) {
+	t.Parallel()
+
+	const input = `{
+		"kind": "any",
+		"inner": [
+			"food:xid",
+			{"kind": "start_end", "start": "barn"},
+			{"kind": "contains", "text": "ogre"},
+			{"kind": "regex", "regex": "^[a-z]{8}"},
+			{"kind": "repeat", "length": 5}
+		],
+		"exclude": [{"kind": "contains", "text": "onion"}]
+	}`
+
+	var spec shrek.MatcherSpec
+	if err := json.Unmarshal([]byte(input), &spec); err != nil {
+		t.Fatalf("could not unmarshal spec: %v", err)
+	}
+
+	if spec.Inner[0].Kind != shrek.SpecKindPattern || spec.Inner[0].Pattern != "food:xid" {
+		t.Errorf("pattern shorthand not decoded correctly, got: %#v", spec.Inner[0])
+	}
+
+	// Round-trip the spec through JSON and check nothing was lost.
+	data, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatalf("could not marshal spec: %v", err)
+	}
+
+	var got shrek.MatcherSpec
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("could not unmarshal marshaled spec: %v", err)
+	}
+	if !reflect.DeepEqual(got, spec) {
+		t.Errorf("spec changed after round-trip, got: %#v, wanted: %#v", got, spec)
+	}
+
+	m, err := spec.Build()
+	if err != nil {
+		t.Fatalf("could not build spec: %v", err)
+	}
+
+	table := []struct {
+		Input string
+		Match bool
+	}{
+		{Input: "foodyjsviqu5fqvqzv5mnfonrapka477vonf6fuko7duolp5g3xid", Match: true},
+		{Input: "barnyjsviqu5fqvqzv5mnfonrapka477vonf6fuko7duolp5g3xid", Match: true},
+		{Input: "2222yjsviqu5fqvqzv5mnfogrepka477vonf6fuko7duolp5g3xid", Match: true},
+		{Input: "abcdefghiqu5fqvqzv5mnfonrapka477vonf6fuko7duolp5g3xid", Match: true},
+		{Input: "2222222hiqu5fqvqzv5mnfonrapka477vonf6fuko7duolp5g3xid", Match: true},
+		{Input: "foodyjsviqu5fqvqzv5mnfonionka477vonf6fuko7duolp5g3xid", Match: false},
+		{Input: "2222yjsviqu5fqvqzv5mnfonrapka477vonf6fuko7duolp5g3xid", Match: false},
+	}
+
+	for _, tc := range table {
+		input := []byte(tc.Input)
+		if match := m.MatchApprox(input) && m.Match(input); match != tc.Match {
+			t.Errorf("invalid match result for %q: got %v, wanted %v", tc.Input, match, tc.Match)
+		}
+	}
+}
+
+func TestMatcherSpec_UnmarshalJSON_Empty(t *testing.T) {
+	t.Parallel()
+
+	// None of these should give a spec that builds, because an empty spec would match
+	// every address.
+	for _, input := range []string{`null`, `""`, `{}`, `{"kind": "pattern"}`, `{"kind": "any", "inner": [null]}`} {
+		var spec shrek.MatcherSpec
+		if err := json.Unmarshal([]byte(input), &spec); err != nil {
+			continue
+		}
+		if _, err := spec.Build(); err == nil {
+			t.Errorf("expected error for %s, got nil", input)
+		}
+	}
+}
+
+func TestMatcherSpec_Build_Error(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		Name string
+		Spec shrek.MatcherSpec
+	}{
+		{Name: "missing kind", Spec: shrek.MatcherSpec{}},
+		{Name: "unknown kind", Spec: shrek.MatcherSpec{Kind: "unknown"}},
+		{Name: "empty pattern", Spec: shrek.MatcherSpec{Kind: shrek.SpecKindPattern}},
+		{Name: "bad pattern", Spec: shrek.MatcherSpec{Kind: shrek.SpecKindPattern, Pattern: "fo0d"}},
+		{Name: "bad end", Spec: shrek.MatcherSpec{Kind: shrek.SpecKindStartEnd, End: "xyz"}},
+		{Name: "bad contains", Spec: shrek.MatcherSpec{Kind: shrek.SpecKindContains, Text: "ABC"}},
+		{Name: "bad regex", Spec: shrek.MatcherSpec{Kind: shrek.SpecKindRegex, Regex: "(["}},
+		{Name: "bad length", Spec: shrek.MatcherSpec{Kind: shrek.SpecKindRepeat}},
+		{Name: "empty any", Spec: shrek.MatcherSpec{Kind: shrek.SpecKindAny}},
+		{
+			Name: "bad inner",
+			Spec: shrek.MatcherSpec{Kind: shrek.SpecKindAll, Inner: []shrek.MatcherSpec{{Kind: "unknown"}}},
+		},
+		{
+			Name: "bad exclude",
+			Spec: shrek.MatcherSpec{Kind: shrek.SpecKindPattern, Pattern: "a", Exclude: []shrek.MatcherSpec{{}}},
+		},
+	}
+
+	for _, tc := range table {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := tc.Spec.Build(); err == nil {
+				t.Errorf("expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,626 @@
+package shrek
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/innix/shrek/internal/ed25519"
+)
+
+// ErrAddressNotFound is returned by an AddressStore when it doesn't hold the requested
+// address.
+var ErrAddressNotFound = errors.New("shrek: address not found in store")
+
+// AddressStore is a place that onion addresses can be saved to and read back from.
+//
+// Hostnames passed to an AddressStore can be given with or without the .onion TLD, and
+// must be valid v3 onion addresses. Hostnames returned by List include the TLD.
+type AddressStore interface {
+	// Save saves the address, which must have a secret key. It returns
+	// ErrAddressExists if the address has already been saved. The store keeps its own
+	// copy of the keys, so addr can be destroyed afterwards.
+	Save(addr *OnionAddress) error
+
+	// List returns the hostnames of all the saved addresses, sorted.
+	List() ([]string, error)
+
+	// Get returns the saved address with the given hostname, or ErrAddressNotFound. The
+	// address is a copy that belongs to the caller, who should destroy it when done.
+	Get(hostname string) (*OnionAddress, error)
+
+	// Delete removes the saved address with the given hostname, or returns
+	// ErrAddressNotFound.
+	Delete(hostname string) error
+}
+
+// DirStore is an AddressStore that uses the directory layout written by
+// SaveOnionAddress: one sub-directory per address, named after its hostname.
+type DirStore struct {
+	// Dir is the directory that holds the address directories.
+	Dir string
+
+	// SaveOptions are passed to SaveOnionAddress when an address is saved.
+	SaveOptions []SaveOption
+}
+
+func (s DirStore) Save(addr *OnionAddress) error {
+	return SaveOnionAddress(s.Dir, addr, s.SaveOptions...)
+}
+
+func (s DirStore) List() ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("shrek: could not list store directory: %w", err)
+	}
+
+	var hostnames []string
+	for _, e := range entries {
+		// Skip anything that isn't an address directory. Entries are already sorted.
+		if !e.IsDir() {
+			continue
+		}
+		if hostname, err := normalizeHostName(e.Name()); err == nil && hostname == e.Name() {
+			hostnames = append(hostnames, hostname)
+		}
+	}
+
+	return hostnames, nil
+}
+
+func (s DirStore) Get(hostname string) (*OnionAddress, error) {
+	dir, err := s.addressDir(hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadOnionAddress(dir)
+}
+
+func (s DirStore) Delete(hostname string) error {
+	dir, err := s.addressDir(hostname)
+	if err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("shrek: could not delete address directory: %w", err)
+	}
+
+	return nil
+}
+
+// addressDir returns the directory of the saved address, or ErrAddressNotFound if it
+// doesn't exist.
+func (s DirStore) addressDir(hostname string) (string, error) {
+	hostname, err := normalizeHostName(hostname)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(s.Dir, hostname)
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return "", ErrAddressNotFound
+	}
+
+	return dir, nil
+}
+
+// ArchiveFormat is the file format used by an ArchiveStore.
+type ArchiveFormat string
+
+const (
+	// ArchiveTar is an uncompressed tar archive.
+	ArchiveTar = ArchiveFormat("tar")
+
+	// ArchiveZip is a zip archive.
+	ArchiveZip = ArchiveFormat("zip")
+)
+
+// ArchiveStore is an AddressStore that keeps every address in a single tar or zip
+// archive, using the same layout as DirStore inside the archive.
+//
+// The archive is read into memory when the store is opened, and rewritten in full
+// every time an address is saved or deleted, so it's only suited to small numbers of
+// addresses. The archive is replaced atomically, so it's never left half-written.
+type ArchiveStore struct {
+	name   string
+	format ArchiveFormat
+
+	mu    sync.Mutex
+	addrs map[string]*OnionAddress
+}
+
+// OpenArchiveStore opens the archive with the given name and format. The archive
+// doesn't have to exist yet; it's created when the first address is saved.
+func OpenArchiveStore(name string, format ArchiveFormat) (*ArchiveStore, error) {
+	s := &ArchiveStore{
+		name:   name,
+		format: format,
+		addrs:  make(map[string]*OnionAddress),
+	}
+
+	data, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("shrek: could not read archive: %w", err)
+	}
+
+	files := make(map[string][]byte)
+	switch format {
+	case ArchiveTar:
+		err = readTarFiles(bytes.NewReader(data), files)
+	case ArchiveZip:
+		err = readZipFiles(bytes.NewReader(data), int64(len(data)), files)
+	default:
+		return nil, fmt.Errorf("shrek: unknown archive format: %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("shrek: could not read archive: %w", err)
+	}
+
+	// The archive holds secret keys, which are copied out of it by parseKeyFiles.
+	defer func() {
+		ed25519.Wipe(data)
+		for _, f := range files {
+			ed25519.Wipe(f)
+		}
+	}()
+
+	for name := range files {
+		dir, file := path.Split(name)
+		if file != PublicKeyFileName {
+			continue
+		}
+
+		addr, err := parseKeyFiles(files[name], files[dir+SecretKeyFileName])
+		if err != nil {
+			return nil, fmt.Errorf("shrek: could not read %q from archive: %w", dir, err)
+		}
+
+		hostname := addr.HostNameString()
+		if hostname+"/" != dir {
+			return nil, fmt.Errorf("shrek: archive directory %q holds keys for %q", dir, hostname)
+		}
+		s.addrs[hostname] = addr
+	}
+
+	return s, nil
+}
+
+func (s *ArchiveStore) Save(addr *OnionAddress) error {
+	if _, err := addr.SecretKeyFile(); err != nil {
+		return fmt.Errorf("shrek: could not save secret key to archive: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hostname := addr.HostNameString()
+	if _, ok := s.addrs[hostname]; ok {
+		return fmt.Errorf("%w: %q", ErrAddressExists, hostname)
+	}
+	s.addrs[hostname] = cloneOnionAddress(addr)
+
+	if err := s.write(); err != nil {
+		// Put things back how they were, so memory stays in sync with the file.
+		s.addrs[hostname].Destroy()
+		delete(s.addrs, hostname)
+		return err
+	}
+
+	return nil
+}
+
+func (s *ArchiveStore) List() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return sortedHostNames(s.addrs), nil
+}
+
+func (s *ArchiveStore) Get(hostname string) (*OnionAddress, error) {
+	hostname, err := normalizeHostName(hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	addr, ok := s.addrs[hostname]
+	if !ok {
+		return nil, ErrAddressNotFound
+	}
+
+	return cloneOnionAddress(addr), nil
+}
+
+func (s *ArchiveStore) Delete(hostname string) error {
+	hostname, err := normalizeHostName(hostname)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	addr, ok := s.addrs[hostname]
+	if !ok {
+		return ErrAddressNotFound
+	}
+	delete(s.addrs, hostname)
+
+	if err := s.write(); err != nil {
+		s.addrs[hostname] = addr
+		return err
+	}
+	addr.Destroy()
+
+	return nil
+}
+
+// write encodes every address into a new archive, then moves it over the old one.
+func (s *ArchiveStore) write() error {
+	var buf bytes.Buffer
+	var err error
+	switch s.format {
+	case ArchiveTar:
+		err = s.writeTar(&buf)
+	case ArchiveZip:
+		err = s.writeZip(&buf)
+	default:
+		err = fmt.Errorf("shrek: unknown archive format: %q", s.format)
+	}
+	if err != nil {
+		return err
+	}
+	defer ed25519.Wipe(buf.Bytes())
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.name), "."+filepath.Base(s.name)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("shrek: could not create archive: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("shrek: could not write archive: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("shrek: could not write archive: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("shrek: could not write archive: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.name); err != nil {
+		return fmt.Errorf("shrek: could not replace archive: %w", err)
+	}
+
+	return nil
+}
+
+func (s *ArchiveStore) writeTar(w io.Writer) error {
+	tw := tar.NewWriter(w)
+	for _, hostname := range sortedHostNames(s.addrs) {
+		if err := writeAddressTar(tw, s.addrs[hostname]); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("shrek: could not write tar archive: %w", err)
+	}
+
+	return nil
+}
+
+func (s *ArchiveStore) writeZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for _, hostname := range sortedHostNames(s.addrs) {
+		if err := writeAddressZip(zw, s.addrs[hostname]); err != nil {
+			return err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("shrek: could not write zip archive: %w", err)
+	}
+
+	return nil
+}
+
+// TarWriter streams addresses to a tar archive as they're saved, using the same layout
+// as DirStore inside the archive. Unlike ArchiveStore, it never reads the archive back,
+// so it can write to a pipe such as stdout. It's safe for concurrent use.
+type TarWriter struct {
+	mu sync.Mutex
+	tw *tar.Writer
+}
+
+// NewTarWriter returns a TarWriter that writes the archive to w.
+func NewTarWriter(w io.Writer) *TarWriter {
+	return &TarWriter{tw: tar.NewWriter(w)}
+}
+
+// Save writes the address to the archive and flushes it to the underlying writer.
+func (w *TarWriter) Save(addr *OnionAddress) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := writeAddressTar(w.tw, addr); err != nil {
+		return err
+	}
+	if err := w.tw.Flush(); err != nil {
+		return fmt.Errorf("shrek: could not write tar archive: %w", err)
+	}
+
+	return nil
+}
+
+// Close writes the end of the archive. It doesn't close the underlying writer.
+func (w *TarWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.tw.Close(); err != nil {
+		return fmt.Errorf("shrek: could not write tar archive: %w", err)
+	}
+
+	return nil
+}
+
+// MemoryStore is an AddressStore that keeps addresses in memory. It's mostly useful
+// for tests. It's safe for concurrent use.
+type MemoryStore struct {
+	mu    sync.Mutex
+	addrs map[string]*OnionAddress
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{addrs: make(map[string]*OnionAddress)}
+}
+
+func (s *MemoryStore) Save(addr *OnionAddress) error {
+	if _, err := addr.SecretKeyFile(); err != nil {
+		return fmt.Errorf("shrek: could not save secret key to memory: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hostname := addr.HostNameString()
+	if _, ok := s.addrs[hostname]; ok {
+		return fmt.Errorf("%w: %q", ErrAddressExists, hostname)
+	}
+	s.addrs[hostname] = cloneOnionAddress(addr)
+
+	return nil
+}
+
+func (s *MemoryStore) List() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return sortedHostNames(s.addrs), nil
+}
+
+func (s *MemoryStore) Get(hostname string) (*OnionAddress, error) {
+	hostname, err := normalizeHostName(hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	addr, ok := s.addrs[hostname]
+	if !ok {
+		return nil, ErrAddressNotFound
+	}
+
+	return cloneOnionAddress(addr), nil
+}
+
+func (s *MemoryStore) Delete(hostname string) error {
+	hostname, err := normalizeHostName(hostname)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	addr, ok := s.addrs[hostname]
+	if !ok {
+		return ErrAddressNotFound
+	}
+	delete(s.addrs, hostname)
+	addr.Destroy()
+
+	return nil
+}
+
+// normalizeHostName validates the hostname and returns it in lowercase with the .onion
+// TLD. Because the hostname is validated, it's also safe to use as a file name.
+func normalizeHostName(hostname string) (string, error) {
+	addr, err := ParseHostName(hostname)
+	if err != nil {
+		return "", err
+	}
+
+	return addr.HostNameString(), nil
+}
+
+// cloneOnionAddress returns a deep copy of addr, so that stores don't share keys with
+// their callers. Otherwise a caller destroying its address would wipe the stored one.
+func cloneOnionAddress(addr *OnionAddress) *OnionAddress {
+	return &OnionAddress{
+		PublicKey: append(ed25519.PublicKey(nil), addr.PublicKey...),
+		SecretKey: append(ed25519.PrivateKey(nil), addr.SecretKey...),
+	}
+}
+
+func sortedHostNames(addrs map[string]*OnionAddress) []string {
+	hostnames := make([]string, 0, len(addrs))
+	for hostname := range addrs {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+
+	return hostnames
+}
+
+type addressFile struct {
+	name string
+	data []byte
+}
+
+// addressFiles returns the files that make up a saved address, with paths relative to
+// the directory that holds the address directory. One of them holds the secret key, so
+// they should be wiped with wipeAddressFiles once they've been written.
+func addressFiles(addr *OnionAddress) ([]addressFile, error) {
+	skData, err := addr.SecretKeyFile()
+	if err != nil {
+		return nil, fmt.Errorf("shrek: could not save secret key to archive: %w", err)
+	}
+
+	hostname := addr.HostNameString()
+	return []addressFile{
+		{name: path.Join(hostname, PublicKeyFileName), data: addr.PublicKeyFile()},
+		{name: path.Join(hostname, SecretKeyFileName), data: skData},
+		{name: path.Join(hostname, HostNameFileName), data: []byte(hostname)},
+	}, nil
+}
+
+func wipeAddressFiles(files []addressFile) {
+	for _, f := range files {
+		ed25519.Wipe(f.data)
+	}
+}
+
+// writeAddressTar writes the directory and files of a saved address to a tar archive.
+func writeAddressTar(tw *tar.Writer, addr *OnionAddress) error {
+	files, err := addressFiles(addr)
+	if err != nil {
+		return err
+	}
+	defer wipeAddressFiles(files)
+
+	now := time.Now()
+	hdr := &tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     addr.HostNameString() + "/",
+		Mode:     0o700,
+		ModTime:  now,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("shrek: could not write tar archive: %w", err)
+	}
+
+	for _, f := range files {
+		hdr := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     f.name,
+			Mode:     0o600,
+			Size:     int64(len(f.data)),
+			ModTime:  now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("shrek: could not write tar archive: %w", err)
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return fmt.Errorf("shrek: could not write tar archive: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// writeAddressZip writes the files of a saved address to a zip archive.
+func writeAddressZip(zw *zip.Writer, addr *OnionAddress) error {
+	files, err := addressFiles(addr)
+	if err != nil {
+		return err
+	}
+	defer wipeAddressFiles(files)
+
+	for _, f := range files {
+		fh := &zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: time.Now()}
+		fh.SetMode(0o600)
+
+		fw, err := zw.CreateHeader(fh)
+		if err != nil {
+			return fmt.Errorf("shrek: could not write zip archive: %w", err)
+		}
+		if _, err := fw.Write(f.data); err != nil {
+			return fmt.Errorf("shrek: could not write zip archive: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func readTarFiles(r io.Reader, files map[string][]byte) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		files[path.Clean(hdr.Name)] = data
+	}
+}
+
+func readZipFiles(r io.ReaderAt, size int64, files map[string][]byte) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		files[path.Clean(f.Name)] = data
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package shrek_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/innix/shrek"
+)
+
+func TestAddressStore(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name string
+		Open func(t *testing.T, dir string) shrek.AddressStore
+	}{
+		{Name: "dir", Open: func(t *testing.T, dir string) shrek.AddressStore {
+			return shrek.DirStore{Dir: dir}
+		}},
+		{Name: "tar", Open: func(t *testing.T, dir string) shrek.AddressStore {
+			return openArchiveStore(t, filepath.Join(dir, "onions.tar"), shrek.ArchiveTar)
+		}},
+		{Name: "zip", Open: func(t *testing.T, dir string) shrek.AddressStore {
+			return openArchiveStore(t, filepath.Join(dir, "onions.zip"), shrek.ArchiveZip)
+		}},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			testAddressStore(t, tc.Open(t, dir))
+
+			// Reopen the store to check that the saved addresses were persisted.
+			s := tc.Open(t, dir)
+			hostnames, err := s.List()
+			if err != nil {
+				t.Fatalf("could not list reopened store: %v", err)
+			}
+			if len(hostnames) != 1 || hostnames[0] != seedHostname+".onion" {
+				t.Errorf("unexpected hostnames in reopened store: %v", hostnames)
+			}
+		})
+	}
+
+	t.Run("memory", func(t *testing.T) {
+		t.Parallel()
+		testAddressStore(t, shrek.NewMemoryStore())
+	})
+}
+
+// testAddressStore saves 2 addresses to the store, deletes one of them, and leaves the
+// seed address in the store.
+func testAddressStore(t *testing.T, s shrek.AddressStore) {
+	t.Helper()
+
+	addr, err := shrek.GenerateOnionAddress(bytes.NewBufferString(seed))
+	if err != nil {
+		t.Fatalf("could not generate the prerequisite onion address: %v", err)
+	}
+	other, err := shrek.GenerateOnionAddress(rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate the prerequisite onion address: %v", err)
+	}
+
+	if _, err := s.Get(seedHostname); !errors.Is(err, shrek.ErrAddressNotFound) {
+		t.Errorf("expected ErrAddressNotFound from empty store, got: %v", err)
+	}
+	if err := s.Save(&shrek.OnionAddress{PublicKey: addr.PublicKey}); err == nil {
+		t.Errorf("expected error saving address without secret key")
+	}
+
+	for _, a := range []*shrek.OnionAddress{addr, other} {
+		if err := s.Save(a); err != nil {
+			t.Fatalf("could not save address: %v", err)
+		}
+	}
+
+	// The store keeps its own copy, so destroying the saved address or the one returned
+	// by Get doesn't affect it.
+	otherSK := append([]byte(nil), other.SecretKey...)
+	other.Destroy()
+	for i := 0; i < 2; i++ {
+		got, err := s.Get(other.HostNameString())
+		if err != nil {
+			t.Fatalf("could not get address: %v", err)
+		}
+		if !bytes.Equal(got.SecretKey, otherSK) {
+			t.Fatalf("store lost secret key after the address was destroyed")
+		}
+		got.Destroy()
+	}
+
+	if err := s.Save(addr); !errors.Is(err, shrek.ErrAddressExists) {
+		t.Errorf("expected ErrAddressExists saving address twice, got: %v", err)
+	}
+
+	hostnames, err := s.List()
+	if err != nil {
+		t.Fatalf("could not list store: %v", err)
+	}
+	if len(hostnames) != 2 || hostnames[0] > hostnames[1] {
+		t.Errorf("expected 2 sorted hostnames, got: %v", hostnames)
+	}
+
+	// Lookups accept hostnames with or without the TLD, in any case.
+	got, err := s.Get(strings.ToUpper(seedHostname))
+	if err != nil {
+		t.Fatalf("could not get address: %v", err)
+	}
+	assertSameAddress(t, got, addr)
+
+	if err := s.Delete(other.HostNameString()); err != nil {
+		t.Fatalf("could not delete address: %v", err)
+	}
+	if err := s.Delete(other.HostNameString()); !errors.Is(err, shrek.ErrAddressNotFound) {
+		t.Errorf("expected ErrAddressNotFound deleting address twice, got: %v", err)
+	}
+	if _, err := s.Get("../../etc"); err == nil {
+		t.Errorf("expected error getting invalid hostname")
+	}
+}
+
+func TestTarWriter(t *testing.T) {
+	t.Parallel()
+
+	addr, err := shrek.GenerateOnionAddress(bytes.NewBufferString(seed))
+	if err != nil {
+		t.Fatalf("could not generate the prerequisite onion address: %v", err)
+	}
+
+	var buf bytes.Buffer
+	w := shrek.NewTarWriter(&buf)
+	if err := w.Save(addr); err != nil {
+		t.Fatalf("could not save address: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("could not close archive: %v", err)
+	}
+
+	var names []string
+	tr := tar.NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("could not read archive: %v", err)
+		}
+		names = append(names, hdr.Name)
+	}
+
+	dir := seedHostname + ".onion/"
+	wanted := []string{dir, dir + shrek.PublicKeyFileName, dir + shrek.SecretKeyFileName, dir + shrek.HostNameFileName}
+	if strings.Join(names, ",") != strings.Join(wanted, ",") {
+		t.Errorf("unexpected archive entries, got: %v, wanted: %v", names, wanted)
+	}
+}
+
+func openArchiveStore(t *testing.T, name string, format shrek.ArchiveFormat) *shrek.ArchiveStore {
+	t.Helper()
+
+	s, err := shrek.OpenArchiveStore(name, format)
+	if err != nil {
+		t.Fatalf("could not open archive store: %v", err)
+	}
+
+	return s
+}
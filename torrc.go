@@ -0,0 +1,154 @@
+package shrek
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// TorrcSnippetFileName is the name of the file that SaveOnionAddress writes the torrc
+// snippet to, when the WithTorrcSnippet option is used.
+const TorrcSnippetFileName = "torrc.snippet"
+
+// DefaultTorDir is the directory that Tor's hidden service directories are placed in
+// when TorrcConfig.Dir is empty. It's the data directory used by most Tor packages.
+const DefaultTorDir = "/var/lib/tor"
+
+// HiddenServicePort is a HiddenServicePort line of a torrc, which makes Tor forward
+// connections made to a port of the onion address on to a target.
+type HiddenServicePort struct {
+	// VirtualPort is the port that clients connect to on the onion address.
+	VirtualPort int
+
+	// Target is where Tor forwards connections to. It can be a port, an address and
+	// port such as "127.0.0.1:8080", or a Unix socket such as "unix:/run/app.sock". If
+	// it's empty, connections are forwarded to VirtualPort on localhost.
+	Target string
+}
+
+// ParseHiddenServicePort parses a port in the form "virtual[:target]", e.g. "80",
+// "80:8080", or "80:127.0.0.1:8080".
+func ParseHiddenServicePort(s string) (HiddenServicePort, error) {
+	virt, target := s, ""
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		virt, target = s[:i], s[i+1:]
+	}
+
+	port, err := strconv.Atoi(virt)
+	if err != nil {
+		return HiddenServicePort{}, fmt.Errorf("shrek: port %q has an invalid virtual port: %q", s, virt)
+	}
+
+	p := HiddenServicePort{VirtualPort: port, Target: target}
+	if err := p.validate(); err != nil {
+		return HiddenServicePort{}, err
+	}
+
+	return p, nil
+}
+
+func (p HiddenServicePort) validate() error {
+	if p.VirtualPort < 1 || p.VirtualPort > 65535 {
+		return fmt.Errorf("shrek: virtual port must be between 1 and 65535: %d", p.VirtualPort)
+	}
+	if strings.ContainsAny(p.Target, " \t\r\n") {
+		return fmt.Errorf("shrek: port target must not contain whitespace: %q", p.Target)
+	}
+
+	return nil
+}
+
+// TorrcConfig configures the torrc lines generated for an onion address.
+type TorrcConfig struct {
+	// Dir is the directory that the key directory is copied into on the machine that
+	// runs Tor. The HiddenServiceDir is Dir followed by the hostname. If it's empty,
+	// DefaultTorDir is used.
+	Dir string
+
+	// Ports are the ports that the onion service listens on. If there are none, port
+	// 80 is forwarded to port 80 on localhost.
+	Ports []HiddenServicePort
+}
+
+// TorrcSnippet returns the torrc lines that configure Tor to host the onion address,
+// e.g.:
+//
+//   # food7pzkbm3a6rj5ubjxgcwfx4xhrzekdzl4ljtrepuvmh7woo2ilkid.onion
+//   HiddenServiceDir /var/lib/tor/food7pzkbm3a6rj5ubjxgcwfx4xhrzekdzl4ljtrepuvmh7woo2ilkid.onion
+//   HiddenServicePort 80 127.0.0.1:80
+//
+// The snippets of several addresses can be concatenated to make a combined torrc.
+func (addr *OnionAddress) TorrcSnippet(cfg TorrcConfig) ([]byte, error) {
+	dir := cfg.Dir
+	if dir == "" {
+		dir = DefaultTorDir
+	}
+
+	ports := cfg.Ports
+	if len(ports) == 0 {
+		ports = []HiddenServicePort{{VirtualPort: 80}}
+	}
+
+	hostname := addr.HostNameString()
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# %s\n", hostname)
+	fmt.Fprintf(&buf, "HiddenServiceDir %s\n", quoteTorrcValue(path.Join(dir, hostname)))
+	for _, p := range ports {
+		if err := p.validate(); err != nil {
+			return nil, err
+		}
+
+		target := p.Target
+		if target == "" {
+			target = fmt.Sprintf("127.0.0.1:%d", p.VirtualPort)
+		}
+		fmt.Fprintf(&buf, "HiddenServicePort %d %s\n", p.VirtualPort, target)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// WithTorrcSnippet makes SaveOnionAddress also write a torrc snippet for the address,
+// as returned by TorrcSnippet, to a file named TorrcSnippetFileName.
+func WithTorrcSnippet(cfg TorrcConfig) SaveOption {
+	return func(c *saveConfig) {
+		c.torrc = &cfg
+	}
+}
+
+// quoteTorrcValue quotes a torrc value if Tor would otherwise misread it. Tor accepts
+// C-style escapes inside quoted values.
+func quoteTorrcValue(v string) string {
+	if v != "" && !strings.ContainsAny(v, " \t\"\\#") {
+		return v
+	}
+
+	return strconv.Quote(v)
+}
+
+// BuildTorrc returns a torrc that hosts all of the given addresses, made by
+// concatenating their snippets.
+func BuildTorrc(cfg TorrcConfig, addrs ...*OnionAddress) ([]byte, error) {
+	if len(addrs) == 0 {
+		return nil, errors.New("shrek: no addresses to build a torrc for")
+	}
+
+	var buf bytes.Buffer
+	for i, addr := range addrs {
+		snippet, err := addr.TorrcSnippet(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.Write(snippet)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,126 @@
+package shrek_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/innix/shrek"
+)
+
+func TestParseHiddenServicePort(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		Port  string
+		Want  shrek.HiddenServicePort
+		Valid bool
+	}{
+		{Port: "80", Want: shrek.HiddenServicePort{VirtualPort: 80}, Valid: true},
+		{Port: "80:8080", Want: shrek.HiddenServicePort{VirtualPort: 80, Target: "8080"}, Valid: true},
+		{Port: "443:127.0.0.1:8443", Want: shrek.HiddenServicePort{VirtualPort: 443, Target: "127.0.0.1:8443"}, Valid: true},
+		{Port: "80:unix:/run/app.sock", Want: shrek.HiddenServicePort{VirtualPort: 80, Target: "unix:/run/app.sock"}, Valid: true},
+		{Port: "0"},
+		{Port: "65536"},
+		{Port: "http"},
+		{Port: "80:bad target"},
+	}
+
+	for _, tc := range table {
+		tc := tc
+
+		t.Run(tc.Port, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := shrek.ParseHiddenServicePort(tc.Port)
+			if err != nil && tc.Valid {
+				t.Fatalf("unexpected error: %v", err)
+			} else if err == nil && !tc.Valid {
+				t.Fatalf("expected error, got nil")
+			}
+
+			if got != tc.Want {
+				t.Errorf("unexpected port, got: %+v, wanted: %+v", got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestOnionAddress_TorrcSnippet(t *testing.T) {
+	t.Parallel()
+
+	addr, err := shrek.GenerateOnionAddress(bytes.NewBufferString(seed))
+	if err != nil {
+		t.Fatalf("could not generate the prerequisite onion address: %v", err)
+	}
+
+	table := []struct {
+		Name   string
+		Config shrek.TorrcConfig
+		Want   string
+	}{
+		{
+			Name:   "defaults",
+			Config: shrek.TorrcConfig{},
+			Want: "# " + seedHostname + ".onion\n" +
+				"HiddenServiceDir /var/lib/tor/" + seedHostname + ".onion\n" +
+				"HiddenServicePort 80 127.0.0.1:80\n",
+		},
+		{
+			Name: "custom",
+			Config: shrek.TorrcConfig{
+				Dir:   "/srv/tor data",
+				Ports: []shrek.HiddenServicePort{{VirtualPort: 80, Target: "8080"}, {VirtualPort: 443}},
+			},
+			Want: "# " + seedHostname + ".onion\n" +
+				"HiddenServiceDir \"/srv/tor data/" + seedHostname + ".onion\"\n" +
+				"HiddenServicePort 80 8080\n" +
+				"HiddenServicePort 443 127.0.0.1:443\n",
+		},
+	}
+
+	for _, tc := range table {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := addr.TorrcSnippet(tc.Config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tc.Want {
+				t.Errorf("unexpected snippet, got:\n%s\nwanted:\n%s", got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestSaveOnionAddress_TorrcSnippet(t *testing.T) {
+	t.Parallel()
+
+	addr, err := shrek.GenerateOnionAddress(bytes.NewBufferString(seed))
+	if err != nil {
+		t.Fatalf("could not generate the prerequisite onion address: %v", err)
+	}
+
+	cfg := shrek.TorrcConfig{Ports: []shrek.HiddenServicePort{{VirtualPort: 22}}}
+	dir := t.TempDir()
+	if err := shrek.SaveOnionAddress(dir, addr, shrek.WithTorrcSnippet(cfg)); err != nil {
+		t.Fatalf("could not save address: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, seedHostname+".onion", shrek.TorrcSnippetFileName))
+	if err != nil {
+		t.Fatalf("could not read torrc snippet: %v", err)
+	}
+
+	want, err := addr.TorrcSnippet(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("unexpected snippet, got:\n%s\nwanted:\n%s", got, want)
+	}
+}